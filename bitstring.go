@@ -0,0 +1,183 @@
+package rl2020
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types and context of the W3C Bitstring Status List, see https://www.w3.org/TR/vc-bitstring-status-list/
+const (
+	ContextCredentialsV2              = "https://www.w3.org/ns/credentials/v2"
+	TypeBitstringStatusList           = "BitstringStatusList"
+	TypeBitstringStatusListCredential = "BitstringStatusListCredential"
+	TypeBitstringStatusListEntry      = "BitstringStatusListEntry"
+	// multibaseBase64URL is the multibase prefix of the base64url, no padding, encoded list
+	multibaseBase64URL = "u"
+)
+
+// BitstringStatusListEntry is the credential status of a credential using a Bitstring Status List
+type BitstringStatusListEntry struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	StatusPurpose        string `json:"statusPurpose"`
+	StatusListIndex      string `json:"statusListIndex"`
+	StatusListCredential string `json:"statusListCredential"`
+}
+
+// NewBitstringStatusListEntry creates the credential status for the credential at index
+// of the status list published at listCredential
+func NewBitstringStatusListEntry(listCredential string, index int, purpose string) BitstringStatusListEntry {
+	return BitstringStatusListEntry{
+		ID:                   fmt.Sprint(listCredential, "#", index),
+		Type:                 TypeBitstringStatusListEntry,
+		StatusPurpose:        purpose,
+		StatusListIndex:      strconv.Itoa(index),
+		StatusListCredential: listCredential,
+	}
+}
+
+// BitstringStatusList adapts a RevocationList2020 to the credential subject of a
+// BitstringStatusListCredential, sharing the bit storage of the list
+type BitstringStatusList struct {
+	ID   string
+	List *RevocationList2020
+}
+
+// MarshalJSON serializes the list as a BitstringStatusList, the bits are stored with the
+// first index in the most significant bit, gzip compressed and multibase base64url encoded
+func (b BitstringStatusList) MarshalJSON() ([]byte, error) {
+	defer b.List.rLock()()
+	bs := make(bitSet, len(b.List.bitSet))
+	for i, v := range b.List.bitSet {
+		bs[i] = bits.Reverse8(v)
+	}
+	o := b.List.opts
+	o.encoding, o.compression = base64.RawURLEncoding, Gzip
+	ebs, err := pack(bs, o)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		ID            string `json:"id"`
+		Type          string `json:"type"`
+		StatusPurpose string `json:"statusPurpose"`
+		EncodedList   string `json:"encodedList"`
+	}{
+		ID:            b.ID,
+		Type:          TypeBitstringStatusList,
+		StatusPurpose: b.List.Purpose,
+		EncodedList:   multibaseBase64URL + ebs,
+	})
+}
+
+// BitstringStatusListCredential represent a verifiable credential wrapping a BitstringStatusList
+// as its credential subject
+type BitstringStatusListCredential struct {
+	Context           []string            `json:"@context"`
+	ID                string              `json:"id"`
+	Type              []string            `json:"type"`
+	Issuer            string              `json:"issuer"`
+	ValidFrom         time.Time           `json:"validFrom"`
+	CredentialSubject BitstringStatusList `json:"credentialSubject"`
+}
+
+// NewBitstringStatusListCredential creates a new BitstringStatusListCredential issued now by issuer
+// and published at id, its credential subject shares the bit storage of rl
+func NewBitstringStatusListCredential(issuer, id string, rl *RevocationList2020) BitstringStatusListCredential {
+	return BitstringStatusListCredential{
+		Context:           []string{ContextCredentialsV2},
+		ID:                id,
+		Type:              []string{"VerifiableCredential", TypeBitstringStatusListCredential},
+		Issuer:            issuer,
+		ValidFrom:         time.Now().UTC(),
+		CredentialSubject: BitstringStatusList{ID: id + "#list", List: rl},
+	}
+}
+
+// MarshalJSON serializes the credential with the validity date in the
+// RFC3339 UTC format, without fractional seconds
+func (c BitstringStatusListCredential) MarshalJSON() ([]byte, error) {
+	// the alias prevents MarshalJSON from calling itself
+	type credential BitstringStatusListCredential
+	return json.Marshal(struct {
+		credential
+		ValidFrom string `json:"validFrom"`
+	}{
+		credential: credential(c),
+		ValidFrom:  c.ValidFrom.UTC().Format(time.RFC3339),
+	})
+}
+
+// NewRevocationListFromBitstringCredentialJSON parse a json serialized BitstringStatusListCredential
+// and returns its credential subject as a RevocationList2020 with the ID of the credential
+func NewRevocationListFromBitstringCredentialJSON(data []byte, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	var c struct {
+		ID                string   `json:"id"`
+		Type              []string `json:"type"`
+		CredentialSubject struct {
+			Type          string `json:"type"`
+			StatusPurpose string `json:"statusPurpose"`
+			EncodedList   string `json:"encodedList"`
+		} `json:"credentialSubject"`
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return
+	}
+	if !contains(c.Type, TypeBitstringStatusListCredential) {
+		err = fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, c.Type, TypeBitstringStatusListCredential)
+		return
+	}
+	if err = o.validateID(c.ID); err != nil {
+		return
+	}
+	cs := c.CredentialSubject
+	if cs.Type != TypeBitstringStatusList {
+		err = fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, cs.Type, TypeBitstringStatusList)
+		return
+	}
+	if err = checkPurpose(cs.StatusPurpose); err != nil {
+		return
+	}
+	encodedList, ok := strings.CutPrefix(cs.EncodedList, multibaseBase64URL)
+	if !ok {
+		err = withListID(c.ID, fmt.Errorf("%w: missing the multibase prefix %q", ErrCorruptEncodedList, multibaseBase64URL))
+		return
+	}
+	// unpacking detects the encoding and compression, keep the options to pack the list
+	uo := o
+	bs, err := unpack(encodedList, &uo)
+	if err != nil {
+		err = withListID(c.ID, err)
+		return
+	}
+	if err = o.checkBitSet(bs); err != nil {
+		return
+	}
+	for i, v := range bs {
+		bs[i] = bits.Reverse8(v)
+	}
+	ebs, err := pack(bs, o)
+	if err != nil {
+		return
+	}
+	rl = RevocationList2020{
+		ID:          c.ID,
+		Type:        TypeRevocationList2020,
+		Purpose:     cs.StatusPurpose,
+		EncodedList: ebs,
+		bitSet:      bs,
+		opts:        o,
+		mu:          new(sync.RWMutex),
+	}
+	return
+}
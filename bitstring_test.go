@@ -0,0 +1,147 @@
+package rl2020
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the example of the Bitstring Status List specification, an empty 16KB list
+const bitstringCredentialFixture = `{
+	"@context": [
+		"https://www.w3.org/ns/credentials/v2"
+	],
+	"id": "https://example.com/credentials/status/3",
+	"type": ["VerifiableCredential", "BitstringStatusListCredential"],
+	"issuer": "did:example:12345",
+	"validFrom": "2021-04-05T14:27:40Z",
+	"credentialSubject": {
+		"id": "https://example.com/credentials/status/3#list",
+		"type": "BitstringStatusList",
+		"statusPurpose": "revocation",
+		"encodedList": "uH4sIAAAAAAAAA-3BMQEAAADCoPVPbQwfoAAAAAAAAAAAAAAAAAAAAIC3AYbSVKsAQAAA"
+	}
+}`
+
+func TestNewBitstringStatusListCredential(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	c := NewBitstringStatusListCredential("did:example:12345", rl.ID, &rl)
+	assert.WithinDuration(t, time.Now(), c.ValidFrom, time.Minute)
+	c.ValidFrom = time.Date(2021, 4, 5, 14, 27, 40, 123, time.UTC)
+
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+
+	// the gzip header differs between implementations, compare the encoded lists decoded
+	var got, want map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.NoError(t, json.Unmarshal([]byte(bitstringCredentialFixture), &want))
+	gotList := got["credentialSubject"].(map[string]interface{})["encodedList"].(string)
+	wantList := want["credentialSubject"].(map[string]interface{})["encodedList"].(string)
+	assert.True(t, strings.HasPrefix(gotList, "uH4sI"), gotList)
+	assert.NotContains(t, gotList, "=")
+	got["credentialSubject"].(map[string]interface{})["encodedList"] = wantList
+	assert.Equal(t, want, got)
+
+	fromFixture, err := NewRevocationListFromBitstringCredentialJSON([]byte(bitstringCredentialFixture))
+	assert.NoError(t, err)
+	fromOutput, err := NewRevocationListFromBitstringCredentialJSON(data)
+	assert.NoError(t, err)
+	assert.True(t, fromFixture.Equal(&fromOutput))
+	assert.True(t, rl.Equal(&fromFixture))
+	assert.Equal(t, PurposeRevocation, fromFixture.Purpose)
+}
+
+func TestBitstringStatusList_RoundTrip(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16, WithStatusPurpose(PurposeSuspension))
+	c := NewBitstringStatusListCredential("did:example:12345", rl.ID, &rl)
+	// the credential subject shares the bit storage of the list
+	assert.NoError(t, rl.Revoke(0, 9, 131071))
+
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"statusPurpose":"suspension"`)
+
+	got, err := NewRevocationListFromBitstringCredentialJSON(data)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&got))
+	assert.Equal(t, PurposeSuspension, got.Purpose)
+	assert.Equal(t, []int{0, 9, 131071}, got.RevokedIndexes())
+
+	// the first index is the most significant bit
+	o, _ := newOptions()
+	var subject struct {
+		EncodedList string `json:"encodedList"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &struct {
+		CredentialSubject interface{} `json:"credentialSubject"`
+	}{&subject}))
+	bs, err := unpack(strings.TrimPrefix(subject.EncodedList, "u"), &o)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80, 0x40}, []byte(bs[:2]))
+	assert.Equal(t, byte(0x01), bs[len(bs)-1])
+}
+
+func TestNewRevocationListFromBitstringCredentialJSON(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			"PASS: specification example",
+			bitstringCredentialFixture,
+			nil,
+		},
+		{
+			"FAIL: not a bitstring credential",
+			strings.Replace(bitstringCredentialFixture, `"BitstringStatusListCredential"`, `"RevocationList2020Credential"`, 1),
+			fmt.Errorf("unsupported type [VerifiableCredential RevocationList2020Credential], expected BitstringStatusListCredential"),
+		},
+		{
+			"FAIL: not a bitstring subject",
+			strings.Replace(bitstringCredentialFixture, `"type": "BitstringStatusList"`, `"type": "RevocationList2020"`, 1),
+			fmt.Errorf("unsupported type RevocationList2020, expected BitstringStatusList"),
+		},
+		{
+			"FAIL: unsupported purpose",
+			strings.Replace(bitstringCredentialFixture, `"statusPurpose": "revocation"`, `"statusPurpose": "message"`, 1),
+			fmt.Errorf("unsupported status purpose message, expected revocation or suspension"),
+		},
+		{
+			"FAIL: missing multibase prefix",
+			strings.Replace(bitstringCredentialFixture, `"encodedList": "u`, `"encodedList": "`, 1),
+			fmt.Errorf(`revocation list https://example.com/credentials/status/3: corrupt encoded list: missing the multibase prefix "u"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListFromBitstringCredentialJSON([]byte(tt.data))
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/credentials/status/3", rl.ID)
+			assert.Equal(t, 16, rl.Size())
+		})
+	}
+}
+
+func TestNewBitstringStatusListEntry(t *testing.T) {
+	got, err := json.Marshal(NewBitstringStatusListEntry("https://example.com/credentials/status/3", 94567, PurposeRevocation))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "https://example.com/credentials/status/3#94567",
+		"type": "BitstringStatusListEntry",
+		"statusPurpose": "revocation",
+		"statusListIndex": "94567",
+		"statusListCredential": "https://example.com/credentials/status/3"
+	}`, string(got))
+}
@@ -0,0 +1,62 @@
+package rl2020
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// compactSeparator separates the ID from the encoded list in the compact form,
+// it never appears in a base64url encoded list
+const compactSeparator = "|"
+
+// EncodeCompact returns the compact form of the list, "id|encodedList" with the list
+// encoded using the url safe base64 alphabet, to embed it where space is limited,
+// for example in a QR code. The status purpose and the capacity set with NewRevocationListWithBits
+// are not part of the compact form
+func (rl *RevocationList2020) EncodeCompact() (string, error) {
+	defer rl.rLock()()
+	o := rl.opts
+	o.encoding = base64.URLEncoding
+	ebs, err := pack(rl.bitSet, o)
+	if err != nil {
+		return "", err
+	}
+	return rl.ID + compactSeparator + ebs, nil
+}
+
+// DecodeCompact parses the compact form of a list returned by EncodeCompact
+func DecodeCompact(s string, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	i := strings.LastIndex(s, compactSeparator)
+	if i < 0 {
+		err = fmt.Errorf("malformed compact revocation list, missing separator %q", compactSeparator)
+		return
+	}
+	id, encodedList := s[:i], s[i+len(compactSeparator):]
+	if err = o.validateID(id); err != nil {
+		return
+	}
+	bs, err := unpack(encodedList, &o)
+	if err != nil {
+		err = withListID(id, err)
+		return
+	}
+	if err = o.checkBitSet(bs); err != nil {
+		return
+	}
+	rl = RevocationList2020{
+		ID:          id,
+		Type:        TypeRevocationList2020,
+		Purpose:     o.purpose,
+		EncodedList: encodedList,
+		bitSet:      bs,
+		opts:        o,
+		mu:          new(sync.RWMutex),
+	}
+	return
+}
@@ -0,0 +1,75 @@
+package rl2020
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationList2020_EncodeCompact(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	// indexes spread over the list produce + and / in the standard encoding
+	for i := 0; i < 2000; i++ {
+		assert.NoError(t, rl.Revoke(i*i%rl.Capacity()))
+	}
+
+	compact, err := rl.EncodeCompact()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(compact, rl.ID+"|"))
+	assert.NotContains(t, strings.TrimPrefix(compact, rl.ID+"|"), "+")
+	assert.NotContains(t, strings.TrimPrefix(compact, rl.ID+"|"), "/")
+
+	got, err := DecodeCompact(compact)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&got))
+	data, err := got.GetBytes()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"https://example.com/credentials/status/3"`)
+}
+
+func TestDecodeCompact(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	compact, _ := rl.EncodeCompact()
+	encodedList := strings.TrimPrefix(compact, "c0|")
+
+	tests := []struct {
+		name    string
+		compact string
+		wantErr error
+	}{
+		{
+			"PASS: compact list",
+			compact,
+			nil,
+		},
+		{
+			"FAIL: missing separator",
+			"c0" + encodedList,
+			fmt.Errorf(`malformed compact revocation list, missing separator "|"`),
+		},
+		{
+			"FAIL: empty ID",
+			"|" + encodedList,
+			fmt.Errorf("revocation list ID is empty"),
+		},
+		{
+			"FAIL: malformed encoded list",
+			"c0|not-base64!",
+			fmt.Errorf("revocation list c0: corrupt encoded list: illegal base64 data at input byte 3"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCompact(tt.compact)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, rl.Equal(&got))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package rl2020
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JSON-LD contexts required by a RevocationList2020Credential
+const (
+	ContextCredentialsV1      = "https://www.w3.org/2018/credentials/v1"
+	ContextRevocationList2020 = "https://w3id.org/vc-revocation-list-2020/v1"
+)
+
+// DefaultContext returns the JSON-LD @context of a RevocationList2020Credential,
+// the verifiable credentials base context followed by the revocation list one
+func DefaultContext() []string {
+	return []string{ContextCredentialsV1, ContextRevocationList2020}
+}
+
+// RevocationList2020Credential represent a verifiable credential wrapping a RevocationList2020
+// as its credential subject, as defined in https://w3c-ccg.github.io/vc-status-rl-2020/#revocationlist2020credential
+type RevocationList2020Credential struct {
+	Context           []string           `json:"@context"`
+	ID                string             `json:"id"`
+	Type              []string           `json:"type"`
+	Issuer            string             `json:"issuer"`
+	IssuanceDate      time.Time          `json:"issuanceDate"`
+	CredentialSubject RevocationList2020 `json:"credentialSubject"`
+}
+
+// NewRevocationListCredential creates a new RevocationList2020Credential issued now by issuer
+func NewRevocationListCredential(issuer, id string, rl RevocationList2020) RevocationList2020Credential {
+	return RevocationList2020Credential{
+		Context:           DefaultContext(),
+		ID:                id,
+		Type:              []string{"VerifiableCredential", TypeRevocationList2020Credential},
+		Issuer:            issuer,
+		IssuanceDate:      time.Now().UTC(),
+		CredentialSubject: rl,
+	}
+}
+
+// MarshalJSON serializes the credential with the issuance date in the
+// RFC3339 UTC format, without fractional seconds. The @context always starts with
+// DefaultContext, followed by any additional context of the credential
+func (c RevocationList2020Credential) MarshalJSON() ([]byte, error) {
+	ctx := DefaultContext()
+	for _, v := range c.Context {
+		if !contains(ctx, v) {
+			ctx = append(ctx, v)
+		}
+	}
+	c.Context = ctx
+	// the alias prevents MarshalJSON from calling itself
+	type credential RevocationList2020Credential
+	// the subject is marshalled through a pointer so that it is read under its lock
+	return json.Marshal(struct {
+		credential
+		IssuanceDate      string              `json:"issuanceDate"`
+		CredentialSubject *RevocationList2020 `json:"credentialSubject"`
+	}{
+		credential:        credential(c),
+		IssuanceDate:      c.IssuanceDate.UTC().Format(time.RFC3339),
+		CredentialSubject: &c.CredentialSubject,
+	})
+}
+
+// NewRevocationListFromCredentialJSON parse a json serialized RevocationList2020Credential
+// and returns the RevocationList2020 of its credential subject
+func NewRevocationListFromCredentialJSON(data []byte, opts ...Option) (rl RevocationList2020, err error) {
+	var c struct {
+		Context           []string        `json:"@context"`
+		Type              []string        `json:"type"`
+		CredentialSubject json.RawMessage `json:"credentialSubject"`
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return
+	}
+	if !contains(c.Context, ContextRevocationList2020) {
+		err = fmt.Errorf("credential context must include %v", ContextRevocationList2020)
+		return
+	}
+	if !contains(c.Type, TypeRevocationList2020Credential) {
+		err = fmt.Errorf("unsupported credential type %v, expected %v", c.Type, TypeRevocationList2020Credential)
+		return
+	}
+	if len(c.CredentialSubject) == 0 {
+		err = fmt.Errorf("credential has no credential subject")
+		return
+	}
+	return NewRevocationListFromJSON(c.CredentialSubject, opts...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
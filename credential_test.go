@@ -0,0 +1,235 @@
+package rl2020
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRevocationListCredential(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		issuer string
+		id     string
+		revoke []int
+		want   string
+	}{
+		{
+			"PASS: spec compliant credential",
+			"did:example:12345",
+			"https://example.com/credentials/status/3",
+			[]int{7812},
+			`{
+				"@context": [
+					"https://www.w3.org/2018/credentials/v1",
+					"https://w3id.org/vc-revocation-list-2020/v1"
+				],
+				"id": "https://example.com/credentials/status/3",
+				"type": [
+					"VerifiableCredential",
+					"RevocationList2020Credential"
+				],
+				"issuer": "did:example:12345",
+				"issuanceDate": "2020-04-05T14:27:40Z",
+				"credentialSubject": {
+					"id": "https://example.com/credentials/status/3",
+					"type": "RevocationList2020",
+					"encodedList": "eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="
+				}
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList(tt.id, 16)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(tt.revoke...))
+
+			c := NewRevocationListCredential(tt.issuer, tt.id, rl)
+			assert.WithinDuration(t, time.Now(), c.IssuanceDate, time.Minute)
+			c.IssuanceDate = time.Date(2020, 4, 5, 14, 27, 40, 123, time.UTC)
+
+			got, err := json.Marshal(c)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNewRevocationListFromCredentialJSON(t *testing.T) {
+
+	fixture, err := os.ReadFile("examples/rl2020.credential.json")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantID  string
+		revoked []int
+		wantErr error
+	}{
+		{
+			"PASS: credential fixture",
+			fixture,
+			"https://example.com/credentials/status/3",
+			[]int{10, 100, 1000, 10000, 94567},
+			nil,
+		},
+		{
+			"FAIL: missing revocation list context",
+			[]byte(`{
+				"@context": ["https://www.w3.org/2018/credentials/v1"],
+				"type": ["VerifiableCredential", "RevocationList2020Credential"],
+				"credentialSubject": {"id": "c0", "type": "RevocationList2020", "encodedList": "eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}
+			}`),
+			"",
+			nil,
+			fmt.Errorf("credential context must include https://w3id.org/vc-revocation-list-2020/v1"),
+		},
+		{
+			"FAIL: wrong credential type",
+			[]byte(`{
+				"@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/vc-revocation-list-2020/v1"],
+				"type": ["VerifiableCredential"],
+				"credentialSubject": {"id": "c0", "type": "RevocationList2020", "encodedList": "eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}
+			}`),
+			"",
+			nil,
+			fmt.Errorf("unsupported credential type [VerifiableCredential], expected RevocationList2020Credential"),
+		},
+		{
+			"FAIL: wrong subject type",
+			[]byte(`{
+				"@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/vc-revocation-list-2020/v1"],
+				"type": ["VerifiableCredential", "RevocationList2020Credential"],
+				"credentialSubject": {"id": "c0", "type": "StatusList2021", "encodedList": "eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}
+			}`),
+			"",
+			nil,
+			fmt.Errorf("unsupported type StatusList2021, expected RevocationList2020"),
+		},
+		{
+			"FAIL: missing subject",
+			[]byte(`{
+				"@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/vc-revocation-list-2020/v1"],
+				"type": ["VerifiableCredential", "RevocationList2020Credential"]
+			}`),
+			"",
+			nil,
+			fmt.Errorf("credential has no credential subject"),
+		},
+		{
+			"FAIL: not a json document",
+			[]byte(`not json`),
+			"",
+			nil,
+			fmt.Errorf("invalid character 'o' in literal null (expecting 'u')"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListFromCredentialJSON(tt.data)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantID, rl.ID)
+				assert.Equal(t, tt.revoked, rl.RevokedIndexes())
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestRevocationList2020Credential_RoundTrip(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(10, 1000))
+	data, err := json.Marshal(NewRevocationListCredential("did:example:12345", "https://example.com/credentials/status/3", rl))
+	assert.NoError(t, err)
+	got, err := NewRevocationListFromCredentialJSON(data)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&got))
+}
+
+func TestDefaultContext(t *testing.T) {
+	assert.Equal(t, "https://www.w3.org/2018/credentials/v1", ContextCredentialsV1)
+	assert.Equal(t, "https://w3id.org/vc-revocation-list-2020/v1", ContextRevocationList2020)
+	assert.Equal(t, []string{ContextCredentialsV1, ContextRevocationList2020}, DefaultContext())
+
+	tests := []struct {
+		name    string
+		context []string
+		want    []string
+	}{
+		{
+			"PASS: missing context",
+			nil,
+			DefaultContext(),
+		},
+		{
+			"PASS: default context",
+			DefaultContext(),
+			DefaultContext(),
+		},
+		{
+			"PASS: additional context",
+			[]string{ContextRevocationList2020, "https://example.com/context/v1"},
+			[]string{ContextCredentialsV1, ContextRevocationList2020, "https://example.com/context/v1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			c := NewRevocationListCredential("did:example:12345", "c0", rl)
+			c.Context = tt.context
+			data, err := json.Marshal(c)
+			assert.NoError(t, err)
+			var got struct {
+				Context []string `json:"@context"`
+			}
+			assert.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, tt.want, got.Context)
+		})
+	}
+}
+
+func TestRevocationList2020_Extras(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(10))
+	subject := fmt.Sprintf(`{"id":"https://example.com/credentials/status/3","type":"RevocationList2020","encodedList":"%s","version":2,"region":"eu-west"}`, rl.EncodedList)
+	data := []byte(fmt.Sprintf(`{"@context":["%s","%s"],"id":"https://example.com/credentials/status/3","type":["VerifiableCredential","RevocationList2020Credential"],"issuer":"did:example:12345","issuanceDate":"2020-04-05T14:27:40Z","credentialSubject":%s}`,
+		ContextCredentialsV1, ContextRevocationList2020, subject))
+
+	got, err := NewRevocationListFromCredentialJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"region":  json.RawMessage(`"eu-west"`),
+		"version": json.RawMessage(`2`),
+	}, got.Extras)
+	assert.Equal(t, []int{10}, got.RevokedIndexes())
+
+	// the extras are emitted after the standard properties, sorted by name
+	out, err := json.Marshal(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"id":"https://example.com/credentials/status/3","type":"RevocationList2020","encodedList":"%s","region":"eu-west","version":2}`, rl.EncodedList), string(out))
+	c, _ := json.Marshal(NewRevocationListCredential("did:example:12345", got.ID, got))
+	again, err := NewRevocationListFromCredentialJSON(c)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Extras, again.Extras)
+
+	// the extras do not override the standard properties
+	got.Extras["encodedList"] = json.RawMessage(`"bogus"`)
+	out, _ = json.Marshal(&got)
+	assert.NotContains(t, string(out), "bogus")
+
+	// lists without extras have none
+	out, _ = json.Marshal(&rl)
+	plain, err := NewRevocationListFromJSON(out)
+	assert.NoError(t, err)
+	assert.Nil(t, plain.Extras)
+}
@@ -0,0 +1,236 @@
+package rl2020
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPError is returned when the server hosting a revocation list replies
+// with a status other than 200 OK
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("fetching %v: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// FetchRevocationList retrieves the revocation list published at url, that is usually
+// the list ID returned by CredentialStatus.Coordinates(). The response can be either a
+// RevocationList2020 or a full RevocationList2020Credential. If client is nil
+// http.DefaultClient is used. Lists embedded in a data: URI are decoded without any request
+func FetchRevocationList(ctx context.Context, client *http.Client, url string, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	var data []byte
+	if strings.HasPrefix(url, dataURIScheme) {
+		data, err = decodeDataURI(url, o)
+	} else {
+		data, err = fetch(ctx, client, url, o)
+	}
+	if err != nil {
+		return
+	}
+	if rl, err = parseListOrCredential(data, opts); err != nil {
+		return
+	}
+	if o.rejectExpired && rl.IsExpired(time.Now()) {
+		err = fmt.Errorf("%w, %v is valid from %v until %v", ErrExpired, rl.ID, formatTime(rl.ValidFrom), formatTime(rl.ValidUntil))
+		rl = RevocationList2020{}
+	}
+	return
+}
+
+// NewRevocationListFromDataURI parses a RevocationList2020 or a RevocationList2020Credential
+// embedded in a data: URI, either base64 ("data:application/json;base64,...") or percent encoded
+func NewRevocationListFromDataURI(uri string, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	data, err := decodeDataURI(uri, o)
+	if err != nil {
+		return
+	}
+	return parseListOrCredential(data, opts)
+}
+
+// dataURIScheme is the scheme of the URIs embedding their content
+const dataURIScheme = "data:"
+
+// decodeDataURI returns the content of a data: URI, the media type is ignored
+func decodeDataURI(uri string, o options) (data []byte, err error) {
+	if !strings.HasPrefix(uri, dataURIScheme) {
+		err = fmt.Errorf("malformed data URI, missing the %q scheme", dataURIScheme)
+		return
+	}
+	mediaType, payload, ok := strings.Cut(strings.TrimPrefix(uri, dataURIScheme), ",")
+	if !ok {
+		err = fmt.Errorf("malformed data URI, missing the ',' separator")
+		return
+	}
+	if len(payload) > 2*o.readLimit() {
+		err = fmt.Errorf("payload exceeds the limit of %d bytes", 2*o.readLimit())
+		return
+	}
+	if strings.HasSuffix(mediaType, ";base64") {
+		if data, err = decode(payload, &o); err != nil {
+			err = fmt.Errorf("malformed data URI: %w", err)
+		}
+		return
+	}
+	s, err := url.PathUnescape(payload)
+	if err != nil {
+		err = fmt.Errorf("malformed data URI: %w", err)
+		return
+	}
+	data = []byte(s)
+	return
+}
+
+// fetch retrieves the payload published at url
+func fetch(ctx context.Context, client *http.Client, url string, o options) (data []byte, err error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		err = &HTTPError{URL: url, StatusCode: res.StatusCode}
+		return
+	}
+	return io.ReadAll(newLimitedReader(res.Body, 2*o.readLimit()))
+}
+
+// parseListOrCredential parses either a RevocationList2020 or a RevocationList2020Credential
+func parseListOrCredential(data []byte, opts []Option) (rl RevocationList2020, err error) {
+	// a credential carries the list in the credential subject
+	var probe struct {
+		CredentialSubject json.RawMessage `json:"credentialSubject"`
+	}
+	if err = json.Unmarshal(data, &probe); err != nil {
+		return
+	}
+	if len(probe.CredentialSubject) > 0 {
+		return NewRevocationListFromCredentialJSON(data, opts...)
+	}
+	return NewRevocationListFromJSON(data, opts...)
+}
+
+// CachingVerifier checks credential statuses against remote revocation lists, each list
+// is fetched once and cached for the TTL. It is safe for concurrent use
+type CachingVerifier struct {
+	client *http.Client
+	ttl    time.Duration
+	opts   []Option
+	now    func() time.Time
+	mu     sync.Mutex
+	lists  map[string]*cachedList
+	swept  time.Time
+}
+
+// cachedList is a fetched revocation list, its lock serializes the fetches of the list.
+// The fetch time is written holding the locks of both the list and the verifier
+type cachedList struct {
+	mu      sync.Mutex
+	rl      *RevocationList2020
+	fetched time.Time
+}
+
+// NewCachingVerifier creates a verifier fetching the lists with client and caching them for ttl,
+// the options are passed to FetchRevocationList. If client is nil http.DefaultClient is used
+func NewCachingVerifier(client *http.Client, ttl time.Duration, opts ...Option) *CachingVerifier {
+	return &CachingVerifier{
+		client: client,
+		ttl:    ttl,
+		opts:   opts,
+		now:    time.Now,
+		lists:  make(map[string]*cachedList),
+	}
+}
+
+// RevocationList returns a copy of the revocation list with the given ID, fetching it if it
+// is not cached or if the cached copy is older than the TTL
+func (v *CachingVerifier) RevocationList(ctx context.Context, id string) (rl RevocationList2020, err error) {
+	cached, err := v.list(ctx, id)
+	if err != nil {
+		return
+	}
+	rl = cached.Clone()
+	return
+}
+
+// list returns the cached revocation list with the given ID, fetching it if needed.
+// The cached list is replaced on every fetch and never modified, so it can be read without copying
+func (v *CachingVerifier) list(ctx context.Context, id string) (rl *RevocationList2020, err error) {
+	v.mu.Lock()
+	v.sweep()
+	cl, ok := v.lists[id]
+	if !ok {
+		cl = new(cachedList)
+		v.lists[id] = cl
+	}
+	v.mu.Unlock()
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.fetched.IsZero() || v.now().Sub(cl.fetched) >= v.ttl {
+		fetched, err := FetchRevocationList(ctx, v.client, id, v.opts...)
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if err != nil {
+			// do not keep the lists that cannot be fetched
+			if v.lists[id] == cl {
+				delete(v.lists, id)
+			}
+			return nil, err
+		}
+		cl.rl, cl.fetched = &fetched, v.now()
+	}
+	rl = cl.rl
+	return
+}
+
+// sweep removes the expired lists from the cache, at most once per TTL.
+// The caller must hold the lock of the verifier
+func (v *CachingVerifier) sweep() {
+	now := v.now()
+	if now.Sub(v.swept) < v.ttl {
+		return
+	}
+	v.swept = now
+	for id, cl := range v.lists {
+		// the lists being fetched for the first time have no fetch time yet
+		if !cl.fetched.IsZero() && now.Sub(cl.fetched) >= v.ttl {
+			delete(v.lists, id)
+		}
+	}
+}
+
+// IsRevoked checks the status of a credential against the cached copy of its revocation list
+func (v *CachingVerifier) IsRevoked(ctx context.Context, cs CredentialStatus) (isIt bool, err error) {
+	id, _ := cs.Coordinates()
+	rl, err := v.list(ctx, id)
+	if err != nil {
+		return
+	}
+	return rl.IsRevoked(cs)
+}
@@ -0,0 +1,276 @@
+package rl2020
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRevocationList(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	list, _ := rl.GetBytes()
+	credential, _ := json.Marshal(NewRevocationListCredential("did:example:12345", rl.ID, rl))
+
+	// serve a different payload on each path
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list":
+			_, _ = w.Write(list)
+		case "/credential":
+			_, _ = w.Write(credential)
+		case "/big":
+			_, _ = w.Write([]byte(strings.Repeat(" ", 512*1024)))
+		case "/slow":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		path    string
+		wantErr error
+	}{
+		{
+			"PASS: bare revocation list",
+			context.Background(),
+			"/list",
+			nil,
+		},
+		{
+			"PASS: full credential",
+			context.Background(),
+			"/credential",
+			nil,
+		},
+		{
+			"FAIL: not found",
+			context.Background(),
+			"/missing",
+			&HTTPError{URL: srv.URL + "/missing", StatusCode: http.StatusNotFound},
+		},
+		{
+			"FAIL: response too big",
+			context.Background(),
+			"/big",
+			fmt.Errorf("payload exceeds the limit of %d bytes", 2*maxBitSetSize*1024),
+		},
+		{
+			"FAIL: cancelled context",
+			cancelled,
+			"/slow",
+			context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchRevocationList(tt.ctx, srv.Client(), srv.URL+tt.path)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, rl.Equal(&got))
+				return
+			}
+			assert.Error(t, err)
+			var httpErr *HTTPError
+			switch {
+			case errors.As(tt.wantErr, &httpErr):
+				var gotErr *HTTPError
+				assert.True(t, errors.As(err, &gotErr))
+				assert.Equal(t, httpErr, gotErr)
+				assert.Equal(t, fmt.Sprintf("fetching %s/missing: unexpected status 404 Not Found", srv.URL), err.Error())
+			case errors.Is(tt.wantErr, context.Canceled):
+				assert.ErrorIs(t, err, context.Canceled)
+			default:
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestFetchRevocationList_RejectExpired(t *testing.T) {
+	now := time.Now().UTC()
+	valid, _ := NewRevocationList("c0", 16)
+	valid.ValidFrom, valid.ValidUntil = now.Add(-time.Hour), now.Add(time.Hour)
+	expired, _ := NewRevocationList("c1", 16)
+	expired.ValidFrom, expired.ValidUntil = now.Add(-2*time.Hour), now.Add(-time.Hour)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rl := valid
+		if r.URL.Path == "/expired" {
+			rl = expired
+		}
+		data, _ := rl.GetBytes()
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			"PASS: valid list",
+			"/valid",
+			[]Option{WithRejectExpired()},
+			false,
+		},
+		{
+			"PASS: expired list accepted by default",
+			"/expired",
+			nil,
+			false,
+		},
+		{
+			"FAIL: expired list",
+			"/expired",
+			[]Option{WithRejectExpired()},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FetchRevocationList(context.Background(), srv.Client(), srv.URL+tt.path, tt.opts...)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrExpired)
+			assert.Equal(t, fmt.Sprintf("revocation list expired, c1 is valid from %v until %v",
+				expired.ValidFrom.Format(time.RFC3339), expired.ValidUntil.Format(time.RFC3339)), err.Error())
+		})
+	}
+}
+
+func TestCachingVerifier(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		rl, _ := NewRevocationList("http://"+r.Host+"/list", 16)
+		_ = rl.Revoke(1, 1000)
+		data, _ := rl.GetBytes()
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	v := NewCachingVerifier(srv.Client(), time.Minute)
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	revoked := NewCredentialStatus(srv.URL+"/list", 1000)
+	active := NewCredentialStatus(srv.URL+"/list", 2)
+
+	// concurrent checks fetch the list once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			isIt, err := v.IsRevoked(ctx, revoked)
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+		}()
+	}
+	wg.Wait()
+	isIt, err := v.IsRevoked(ctx, active)
+	assert.NoError(t, err)
+	assert.False(t, isIt)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// the list is fetched again once the ttl has passed
+	now = now.Add(time.Minute)
+	isIt, err = v.IsRevoked(ctx, revoked)
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// fetch errors are not cached
+	missing := NewCredentialStatus(srv.URL+"/missing", 1)
+	_, err = v.IsRevoked(ctx, missing)
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	_, err = v.IsRevoked(ctx, missing)
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, int32(2), fetches.Load())
+	assert.NotContains(t, v.lists, srv.URL+"/missing")
+
+	// the returned list is a copy
+	rl, err := v.RevocationList(ctx, srv.URL+"/list")
+	assert.NoError(t, err)
+	assert.NoError(t, rl.Reset(1000))
+	isIt, err = v.IsRevoked(ctx, revoked)
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// the expired lists are evicted
+	assert.Len(t, v.lists, 1)
+	now = now.Add(time.Minute)
+	_, err = v.IsRevoked(ctx, missing)
+	assert.Error(t, err)
+	assert.Empty(t, v.lists)
+}
+
+func TestNewRevocationListFromDataURI(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	list, _ := rl.GetBytes()
+	credential, _ := json.Marshal(NewRevocationListCredential("did:example:12345", rl.ID, rl))
+
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr error
+	}{
+		{"PASS: base64 list", "data:application/json;base64," + base64.StdEncoding.EncodeToString(list), nil},
+		{"PASS: base64 credential", "data:application/vc+ld+json;base64," + base64.StdEncoding.EncodeToString(credential), nil},
+		{"PASS: unpadded base64", "data:;base64," + base64.RawURLEncoding.EncodeToString(list), nil},
+		{"PASS: percent encoded list", "data:application/json," + url.PathEscape(string(list)), nil},
+		{"PASS: percent encoded credential", "data:," + url.PathEscape(string(credential)), nil},
+		{"FAIL: missing separator", "data:application/json;base64", fmt.Errorf("malformed data URI, missing the ',' separator")},
+		{"FAIL: not a data URI", "https://example.com/credentials/status/3", fmt.Errorf(`malformed data URI, missing the "data:" scheme`)},
+		{"FAIL: malformed base64", "data:application/json;base64,not-base64!", fmt.Errorf("malformed data URI: illegal base64 data at input byte 3")},
+		{"FAIL: malformed percent encoding", "data:application/json,%zz", errors.New(`malformed data URI: invalid URL escape "%zz"`)},
+		{"FAIL: payload too big", "data:," + strings.Repeat(" ", 512*1024), fmt.Errorf("payload exceeds the limit of %d bytes", 2*maxBitSetSize*1024)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRevocationListFromDataURI(tt.uri)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, rl.Equal(&got))
+			// data URIs are decoded without any request
+			got, err = FetchRevocationList(context.Background(), nil, tt.uri)
+			assert.NoError(t, err)
+			assert.True(t, rl.Equal(&got))
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package rl2020
+
+import (
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// Compression is the algorithm used to compress the bit set before encoding it
+type Compression int
+
+const (
+	// Zlib compression, as specified by RevocationList2020
+	Zlib Compression = iota
+	// Gzip compression, as specified by StatusList2021
+	Gzip
+	// Uncompressed stores the raw bit set, useful for debugging
+	Uncompressed
+)
+
+// String returns the name of the compression
+func (c Compression) String() string {
+	switch c {
+	case Zlib:
+		return "zlib"
+	case Gzip:
+		return "gzip"
+	case Uncompressed:
+		return "uncompressed"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Option configures how a revocation list is encoded and decoded
+type Option func(*options) error
+
+type options struct {
+	encoding           *base64.Encoding
+	compression        Compression
+	compressionLevel   int
+	decompressionLimit int // zero means the maximum list size
+	purpose            string
+	idValidator        func(id string) error // nil means validateID
+	rejectExpired      bool
+	minimumSize        int
+	observer           ObserverFunc
+	maxRevocations     int
+	maxSize            int
+	logger             *slog.Logger // nil disables logging
+	trustedInput       bool
+	idNormalizer       func(id string) string // nil means IDs are compared as they are
+	compactEmpty       bool
+	withRevision       bool
+}
+
+func newOptions(opts ...Option) (o options, err error) {
+	o = options{
+		encoding:         base64.StdEncoding,
+		compression:      Zlib,
+		compressionLevel: zlib.DefaultCompression,
+		purpose:          PurposeRevocation,
+		minimumSize:      minBitSetSize,
+		maxSize:          maxBitSetSize,
+	}
+	for _, opt := range opts {
+		if err = opt(&o); err != nil {
+			return
+		}
+	}
+	// the minimum size is checked once the maximum size is known
+	err = o.checkSize(o.minimumSize)
+	return
+}
+
+// WithURLEncoding encodes the list using the url safe base64 alphabet
+// instead of the standard one
+func WithURLEncoding() Option {
+	return func(o *options) error {
+		o.encoding = base64.URLEncoding
+		return nil
+	}
+}
+
+// WithCompression selects the compression algorithm used to pack the list. When decoding,
+// zlib and gzip are detected from the header, while uncompressed lists can only be
+// read when Uncompressed is selected
+func WithCompression(c Compression) Option {
+	return func(o *options) error {
+		switch c {
+		case Zlib, Gzip, Uncompressed:
+			o.compression = c
+			return nil
+		}
+		return fmt.Errorf("unsupported compression %d", c)
+	}
+}
+
+// WithCompressionLevel sets the compression level used to pack the list, the level
+// must be one of the levels accepted by compress/zlib, from zlib.HuffmanOnly to zlib.BestCompression
+func WithCompressionLevel(level int) Option {
+	return func(o *options) error {
+		if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+			return fmt.Errorf("compression level must be between %d and %d, got %d", zlib.HuffmanOnly, zlib.BestCompression, level)
+		}
+		o.compressionLevel = level
+		return nil
+	}
+}
+
+// WithDecompressionLimit sets the maximum number of bytes an encoded list is allowed to
+// decompress to, the default is the maximum list size. The limit can only lower that cap,
+// a larger limit is clamped to it: use WithMaxSize to accept larger lists
+func WithDecompressionLimit(limit int) Option {
+	return func(o *options) error {
+		if limit <= 0 {
+			return fmt.Errorf("decompression limit must be positive, got %d", limit)
+		}
+		o.decompressionLimit = limit
+		return nil
+	}
+}
+
+// WithTrustedInput disables the decompression limit, so that encoded lists are decompressed
+// without bounds before their size is checked. It is UNSAFE for untrusted data, since a
+// small encoded list can decompress to an arbitrary amount of memory
+func WithTrustedInput() Option {
+	return func(o *options) error {
+		o.trustedInput = true
+		return nil
+	}
+}
+
+// WithCompactEmpty serializes the lists with no revoked credentials using the marker "empty:"
+// followed by the size of the list in KB instead of the encoded list. The marker is always
+// expanded when parsing, but it is not part of the specification so verifiers using other
+// implementations cannot read it
+func WithCompactEmpty() Option {
+	return func(o *options) error {
+		o.compactEmpty = true
+		return nil
+	}
+}
+
+// WithRevision includes the revision of the list in its JSON serialization, so that clients can
+// compare revisions to find out whether the list changed. The revision is not part of the
+// specification, when present it is always read back when parsing a list
+func WithRevision() Option {
+	return func(o *options) error {
+		o.withRevision = true
+		return nil
+	}
+}
+
+// WithStatusPurpose sets the status purpose of a new list, either PurposeRevocation
+// (the default) or PurposeSuspension. When parsing a list the purpose is read from the list itself
+func WithStatusPurpose(purpose string) Option {
+	return func(o *options) error {
+		if err := checkPurpose(purpose); err != nil {
+			return err
+		}
+		o.purpose = purpose
+		return nil
+	}
+}
+
+// WithMinimumSize raises the minimum size in KB of a new list, for a stronger herd privacy,
+// creating or resizing a list to a smaller size fails. The size must be within the allowed bounds
+func WithMinimumSize(kbSize int) Option {
+	return func(o *options) error {
+		o.minimumSize = kbSize
+		return nil
+	}
+}
+
+// WithMaxSize changes the maximum size in KB of a list, 128 by default, when both the issuer
+// and the verifiers of a list agree on a different bound. It can not be lower than the minimum
+// size of 16KB allowed by the specification
+func WithMaxSize(kbSize int) Option {
+	return func(o *options) error {
+		if kbSize < minBitSetSize {
+			return fmt.Errorf("%w: max size must be at least %d, got %d", ErrSizeOutOfBounds, minBitSetSize, kbSize)
+		}
+		if kbSize > maxAllocSize {
+			return fmt.Errorf("%w: max size must be at most %d, got %d", ErrSizeOutOfBounds, maxAllocSize, kbSize)
+		}
+		o.maxSize = kbSize
+		return nil
+	}
+}
+
+// checkSize verifies that a list size in KB is within the allowed bounds
+func (o options) checkSize(kbSize int) error {
+	if kbSize > o.maxSize || kbSize < minBitSetSize {
+		return fmt.Errorf("%w: must be between %d and %d, got %d", ErrSizeOutOfBounds, minBitSetSize, o.maxSize, kbSize)
+	}
+	return nil
+}
+
+// unpackLimit returns the maximum number of bytes an encoded list can be unpacked to
+func (o options) unpackLimit() int {
+	if o.trustedInput {
+		return math.MaxInt
+	}
+	return o.readLimit()
+}
+
+// checkBitSet verifies that a decoded bit set is a whole number of KB within the allowed bounds
+func (o options) checkBitSet(bs bitSet) error {
+	if err := o.checkSize(bs.size()); err != nil {
+		return err
+	}
+	if len(bs)%1024 != 0 {
+		return fmt.Errorf("%w: decompressed to %d bytes, not a whole number of KB", ErrCorruptEncodedList, len(bs))
+	}
+	return nil
+}
+
+// readLimit returns the maximum number of bytes an encoded list can decompress to,
+// the decompression limit never exceeds the maximum list size
+func (o options) readLimit() int {
+	if o.decompressionLimit > 0 && o.decompressionLimit < o.maxSize*1024 {
+		return o.decompressionLimit
+	}
+	return o.maxSize * 1024
+}
+
+// checkMinimumSize verifies that the size in KB of a new list is not below the minimum size
+func (o options) checkMinimumSize(kbSize int) error {
+	if kbSize < o.minimumSize {
+		return fmt.Errorf("%w: must be at least %d, got %d", ErrSizeOutOfBounds, o.minimumSize, kbSize)
+	}
+	return nil
+}
+
+// WithMaxRevocations limits the number of credentials that can be revoked in the list, the updates
+// that would revoke more credentials fail with ErrQuotaExceeded, while resetting credentials
+// frees the quota
+func WithMaxRevocations(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("max revocations must be positive, got %d", n)
+		}
+		o.maxRevocations = n
+		return nil
+	}
+}
+
+// ObserverFunc is notified of the updates of a list with the action, Revoke or Reset,
+// and the number of credentials whose status changed
+type ObserverFunc func(action bool, count int)
+
+// WithObserver sets a function called after each successful update of the bits of a list,
+// for example to export metrics. Toggle and RestoreSnapshot may both revoke and reset
+// credentials, so they call the observer once for each action that changed any credential.
+// The observer is called while the list is locked, so it must not call the methods of the list
+func WithObserver(observer ObserverFunc) Option {
+	return func(o *options) error {
+		o.observer = observer
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to log, at debug level, how the encoded lists are
+// decoded: the decoded length, the detected compression and the size of the bit set
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// debug logs a diagnostic message if a logger is set
+func (o *options) debug(ctx context.Context, msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.DebugContext(ctx, msg, args...)
+	}
+}
+
+// WithRejectExpired makes FetchRevocationList fail with ErrExpired
+// if the fetched list is outside of its validity window
+func WithRejectExpired() Option {
+	return func(o *options) error {
+		o.rejectExpired = true
+		return nil
+	}
+}
+
+// WithIDValidator sets the function used to validate the ID of a parsed list, for example
+// to only accept URLs under a given namespace. The default validator rejects empty IDs
+func WithIDValidator(validate func(id string) error) Option {
+	return func(o *options) error {
+		if validate == nil {
+			return fmt.Errorf("ID validator must not be nil")
+		}
+		o.idValidator = validate
+		return nil
+	}
+}
+
+// validateID checks the ID of a parsed list using the configured validator
+func (o options) validateID(id string) error {
+	if o.idValidator != nil {
+		return o.idValidator(id)
+	}
+	return validateID(id)
+}
+
+// validateID is the default ID validator, it rejects empty IDs
+func validateID(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return fmt.Errorf("revocation list %w", ErrEmptyID)
+	}
+	return nil
+}
+
+// WithIDNormalizer sets the function used to normalize the list IDs before comparing the ID
+// of a list with the one in a credential status, for example NormalizeID. By default the IDs
+// must match exactly
+func WithIDNormalizer(normalize func(id string) string) Option {
+	return func(o *options) error {
+		if normalize == nil {
+			return fmt.Errorf("ID normalizer must not be nil")
+		}
+		o.idNormalizer = normalize
+		return nil
+	}
+}
+
+// sameID reports whether two list IDs match after normalization
+func (o options) sameID(a, b string) bool {
+	if o.idNormalizer != nil {
+		return o.idNormalizer(a) == o.idNormalizer(b)
+	}
+	return a == b
+}
+
+// NormalizeID lowercases the scheme and host of a list ID and trims the trailing slashes
+func NormalizeID(id string) string {
+	u, err := url.Parse(id)
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(id, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u.String()
+}
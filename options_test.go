@@ -0,0 +1,973 @@
+package rl2020
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithURLEncoding(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		packOpts []Option
+		readOpts []Option
+		wantEnc  *base64.Encoding
+	}{
+		{
+			"PASS: standard encoding round trip",
+			nil,
+			nil,
+			base64.StdEncoding,
+		},
+		{
+			"PASS: url encoding round trip",
+			[]Option{WithURLEncoding()},
+			[]Option{WithURLEncoding()},
+			base64.URLEncoding,
+		},
+		{
+			"PASS: url encoded list read with the default options",
+			[]Option{WithURLEncoding()},
+			nil,
+			base64.URLEncoding,
+		},
+		{
+			"PASS: standard encoded list read with url encoding",
+			nil,
+			[]Option{WithURLEncoding()},
+			base64.StdEncoding,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", 16, tt.packOpts...)
+			assert.NoError(t, err)
+			// revoke enough to get alphabet specific symbols in the payload
+			var idx []int
+			for i := 0; i < 5000; i++ {
+				idx = append(idx, i*i%rl.Capacity())
+			}
+			assert.NoError(t, rl.Revoke(idx...))
+			if tt.wantEnc == base64.URLEncoding {
+				assert.False(t, strings.ContainsAny(rl.EncodedList, "+/"))
+				assert.True(t, strings.ContainsAny(rl.EncodedList, "-_"))
+			} else {
+				assert.True(t, strings.ContainsAny(rl.EncodedList, "+/"))
+			}
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(rlB, tt.readOpts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnc, rlN.opts.encoding)
+			assert.Equal(t, rl.BitSet(), rlN.BitSet())
+			assert.Equal(t, rl.EncodedList, rlN.EncodedList)
+		})
+	}
+}
+
+func TestDecode_PaddingVariants(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	var idx []int
+	for i := 0; i < 5000; i++ {
+		idx = append(idx, i*i%rl.Capacity())
+	}
+	assert.NoError(t, rl.Revoke(idx...))
+	b, _ := base64.StdEncoding.DecodeString(rl.EncodedList)
+	// make sure the payload needs padding
+	for len(b)%3 == 0 {
+		b = append(b, 0)
+	}
+
+	for _, enc := range []struct {
+		name     string
+		encoding *base64.Encoding
+	}{
+		{"standard", base64.StdEncoding},
+		{"standard without padding", base64.RawStdEncoding},
+		{"url", base64.URLEncoding},
+		{"url without padding", base64.RawURLEncoding},
+	} {
+		t.Run("PASS: "+enc.name, func(t *testing.T) {
+			o, _ := newOptions()
+			got, err := decode(enc.encoding.EncodeToString(b), &o)
+			assert.NoError(t, err)
+			assert.Equal(t, b, got)
+			assert.Equal(t, enc.encoding, o.encoding)
+		})
+	}
+
+	// lists are encoded with padded standard base64 by default
+	_, err := base64.StdEncoding.DecodeString(rl.EncodedList)
+	assert.NoError(t, err)
+	rlN, err := NewRevocationListFromJSON([]byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":%q}`,
+		strings.TrimRight(rl.EncodedList, "="))))
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&rlN))
+}
+
+func TestWithCompression(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		packOpts []Option
+		readOpts []Option
+		want     Compression
+		wantErr  error
+	}{
+		{
+			"PASS: zlib round trip",
+			[]Option{WithCompression(Zlib)},
+			nil,
+			Zlib,
+			nil,
+		},
+		{
+			"PASS: gzip round trip",
+			[]Option{WithCompression(Gzip)},
+			nil,
+			Gzip,
+			nil,
+		},
+		{
+			"PASS: gzip list read with zlib configured",
+			[]Option{WithCompression(Gzip)},
+			[]Option{WithCompression(Zlib)},
+			Gzip,
+			nil,
+		},
+		{
+			"PASS: uncompressed round trip",
+			[]Option{WithCompression(Uncompressed)},
+			[]Option{WithCompression(Uncompressed)},
+			Uncompressed,
+			nil,
+		},
+		{
+			"FAIL: unsupported compression",
+			[]Option{WithCompression(Compression(99))},
+			nil,
+			Zlib,
+			fmt.Errorf("unsupported compression 99"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", 16, tt.packOpts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(1, 100, 131071))
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(rlB, tt.readOpts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rlN.opts.compression)
+			assert.Equal(t, rl.BitSet(), rlN.BitSet())
+			assert.Equal(t, rl.EncodedList, rlN.EncodedList)
+		})
+	}
+}
+
+func TestWithCompression_GzipFixture(t *testing.T) {
+	// produced by a gzip based implementation, bits 3 and 131071 are set
+	data := []byte(`{
+		"id": "https://example.com/status/1",
+		"type": "RevocationList2020",
+		"encodedList": "H4sIAIAAWWIC/+3BMQEAAAwCoJ3GXnRj+AA5AAAAAAAAAAAAAAAAAAAAYOwL2AlDcQBAAAA="
+	}`)
+	rl, err := NewRevocationListFromJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, rl.Size())
+	assert.Equal(t, Gzip, rl.opts.compression)
+	assert.Equal(t, []int{3, 131071}, rl.RevokedIndexes())
+}
+
+func TestWithCompression_StatusList2021Fixture(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		encodedList string
+		want        []int
+	}{
+		{
+			// the example of the StatusList2021 specification, an empty 16KB list
+			"PASS: specification example",
+			"H4sIAAAAAAAAA-3BMQEAAADCoPVPbQwfoAAAAAAAAAAAAAAAAAAAAIC3AYbSVKsAQAAA",
+			[]int{},
+		},
+		{
+			"PASS: base64url without padding",
+			"H4sIAIAAWWIC_-3BMQEAAAwCoJ3GXnRj-AA5AAAAAAAAAAAAAAAAAAAAYOwL2AlDcQBAAAA",
+			[]int{3, 131071},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, _ := newOptions()
+			bs, err := unpack(tt.encodedList, &o)
+			assert.NoError(t, err)
+			assert.Equal(t, Gzip, o.compression)
+			assert.Equal(t, 16*1024, len(bs))
+			rl := RevocationList2020{ID: "c0", bitSet: bs}
+			assert.Equal(t, tt.want, rl.RevokedIndexes())
+			// the list is packed back with the same encoding and compression
+			ebs, err := pack(bs, o)
+			assert.NoError(t, err)
+			got, err := unpack(ebs, &o)
+			assert.NoError(t, err)
+			assert.Equal(t, bs, got)
+		})
+	}
+}
+
+func TestWithDecompressionLimit(t *testing.T) {
+
+	// a list that decompresses to 1MB
+	o, _ := newOptions()
+	bomb, _ := pack(make(bitSet, 1024*1024), o)
+	data := []byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s"}`, bomb))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: list within the default limit",
+			[]byte(`{"id":"c0","type":"RevocationList2020","encodedList":"eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}`),
+			nil,
+			nil,
+		},
+		{
+			"FAIL: list exceeds the default limit",
+			data,
+			nil,
+			fmt.Errorf("size out of bounds: must be between %d and %d, got more than %d", minBitSetSize, maxBitSetSize, maxBitSetSize),
+		},
+		{
+			"FAIL: list exceeds a custom limit",
+			[]byte(`{"id":"c0","type":"RevocationList2020","encodedList":"eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}`),
+			[]Option{WithDecompressionLimit(1024)},
+			fmt.Errorf("decompressed list exceeds the limit of 1024 bytes"),
+		},
+		{
+			"FAIL: raised limit still enforces the list size",
+			data,
+			[]Option{WithDecompressionLimit(2 * 1024 * 1024)},
+			fmt.Errorf("size out of bounds: must be between %d and %d, got more than %d", minBitSetSize, maxBitSetSize, maxBitSetSize),
+		},
+		{
+			"PASS: trusted input ignores the limit",
+			[]byte(`{"id":"c0","type":"RevocationList2020","encodedList":"eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}`),
+			[]Option{WithDecompressionLimit(1024), WithTrustedInput()},
+			nil,
+		},
+		{
+			"PASS: trusted input within a raised size",
+			data,
+			[]Option{WithMaxSize(1024), WithDecompressionLimit(1024), WithTrustedInput()},
+			nil,
+		},
+		{
+			"FAIL: trusted input still enforces the list size",
+			data,
+			[]Option{WithTrustedInput()},
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 1024),
+		},
+		{
+			"FAIL: invalid limit",
+			data,
+			[]Option{WithDecompressionLimit(0)},
+			fmt.Errorf("decompression limit must be positive, got 0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRevocationListFromJSON(tt.data, tt.opts...)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+
+	// a limit above the maximum size does not raise the read limits either
+	o, _ = newOptions(WithDecompressionLimit(2 * 1024 * 1024))
+	assert.Equal(t, maxBitSetSize*1024, o.readLimit())
+	o, _ = newOptions(WithDecompressionLimit(512*1024), WithMaxSize(1024))
+	assert.Equal(t, 512*1024, o.readLimit())
+}
+
+func TestWithCompressionLevel(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: default level",
+			nil,
+			nil,
+		},
+		{
+			"PASS: no compression",
+			[]Option{WithCompressionLevel(zlib.NoCompression)},
+			nil,
+		},
+		{
+			"PASS: best speed",
+			[]Option{WithCompressionLevel(zlib.BestSpeed)},
+			nil,
+		},
+		{
+			"PASS: best compression",
+			[]Option{WithCompressionLevel(zlib.BestCompression)},
+			nil,
+		},
+		{
+			"PASS: huffman only with gzip",
+			[]Option{WithCompressionLevel(zlib.HuffmanOnly), WithCompression(Gzip)},
+			nil,
+		},
+		{
+			"FAIL: level too high",
+			[]Option{WithCompressionLevel(10)},
+			fmt.Errorf("compression level must be between -2 and 9, got 10"),
+		},
+		{
+			"FAIL: level too low",
+			[]Option{WithCompressionLevel(-3)},
+			fmt.Errorf("compression level must be between -2 and 9, got -3"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", 16, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(1, 5000, 131071))
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(rlB)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 5000, 131071}, rlN.RevokedIndexes())
+			assert.True(t, rl.Equal(&rlN))
+		})
+	}
+}
+
+func TestWithCompression_Uncompressed(t *testing.T) {
+	rl, err := NewRevocationList("c0", 16, WithCompression(Uncompressed))
+	assert.NoError(t, err)
+	assert.NoError(t, rl.Revoke(0, 9))
+	// the encoded list is the plain base64 of the bit set
+	raw, err := base64.StdEncoding.DecodeString(rl.EncodedList)
+	assert.NoError(t, err)
+	assert.Len(t, raw, 16*1024)
+	assert.Equal(t, []byte{0x01, 0x02, 0x00}, raw[:3])
+	// an uncompressed list exceeding the limit is rejected too
+	_, err = NewRevocationListFromJSON([]byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s"}`, rl.EncodedList)),
+		WithCompression(Uncompressed), WithDecompressionLimit(1024))
+	assert.Error(t, err)
+	assert.Equal(t, "decompressed list exceeds the limit of 1024 bytes", err.Error())
+}
+
+func TestWithCompression_NoDetection(t *testing.T) {
+	// the first two bytes of this bit set, 0x08 0x1d, are a valid zlib header
+	rl, err := NewRevocationList("c0", 16, WithCompression(Uncompressed))
+	assert.NoError(t, err)
+	assert.NoError(t, rl.Revoke(3, 8, 10, 11, 12))
+	rlB, err := rl.GetBytes()
+	assert.NoError(t, err)
+	rlN, err := NewRevocationListFromJSON(rlB, WithCompression(Uncompressed))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 8, 10, 11, 12}, rlN.RevokedIndexes())
+
+	tests := []struct {
+		name     string
+		packOpts []Option
+		readOpts []Option
+	}{
+		{
+			"FAIL: uncompressed list read with zlib configured",
+			[]Option{WithCompression(Uncompressed)},
+			nil,
+		},
+		{
+			"FAIL: uncompressed list read with gzip configured",
+			[]Option{WithCompression(Uncompressed)},
+			[]Option{WithCompression(Gzip)},
+		},
+		{
+			"FAIL: zlib list read with uncompressed configured",
+			[]Option{WithCompression(Zlib)},
+			[]Option{WithCompression(Uncompressed)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", 16, tt.packOpts...)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(1, 100))
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			_, err = NewRevocationListFromJSON(rlB, tt.readOpts...)
+			assert.Error(t, err)
+		})
+	}
+
+	// a bit set starting with the gzip magic bytes is not mistaken for gzip either
+	rl, err = NewRevocationList("c0", 16, WithCompression(Uncompressed))
+	assert.NoError(t, err)
+	assert.NoError(t, rl.Revoke(0, 1, 2, 3, 4, 8, 9, 11, 15))
+	rlB, err = rl.GetBytes()
+	assert.NoError(t, err)
+	rlN, err = NewRevocationListFromJSON(rlB, WithCompression(Uncompressed))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 8, 9, 11, 15}, rlN.RevokedIndexes())
+	_, err = NewRevocationListFromJSON(rlB)
+	assert.ErrorIs(t, err, ErrCorruptEncodedList)
+}
+
+func TestWithStatusPurpose(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		want     string
+		wantJSON string
+		wantErr  error
+	}{
+		{
+			"PASS: default purpose",
+			nil,
+			PurposeRevocation,
+			"",
+			nil,
+		},
+		{
+			"PASS: revocation purpose",
+			[]Option{WithStatusPurpose(PurposeRevocation)},
+			PurposeRevocation,
+			"",
+			nil,
+		},
+		{
+			"PASS: suspension purpose",
+			[]Option{WithStatusPurpose(PurposeSuspension)},
+			PurposeSuspension,
+			`"statusPurpose":"suspension"`,
+			nil,
+		},
+		{
+			"FAIL: unsupported purpose",
+			[]Option{WithStatusPurpose("expiration")},
+			"",
+			"",
+			fmt.Errorf("unsupported status purpose expiration, expected revocation or suspension"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", 16, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rl.Purpose)
+			assert.NoError(t, rl.Revoke(1))
+			data, err := rl.GetBytes()
+			assert.NoError(t, err)
+			if tt.wantJSON == "" {
+				assert.NotContains(t, string(data), "statusPurpose")
+			} else {
+				assert.Contains(t, string(data), tt.wantJSON)
+			}
+			rlN, err := NewRevocationListFromJSON(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rlN.Purpose)
+			assert.Equal(t, rl.Clone().Purpose, rlN.Purpose)
+		})
+	}
+}
+
+func TestWithIDValidator(t *testing.T) {
+	namespace := func(id string) error {
+		u, err := url.Parse(id)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" || u.Host != "example.com" {
+			return fmt.Errorf("revocation list ID %v is not under https://example.com", id)
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: default validator",
+			"c0",
+			nil,
+			nil,
+		},
+		{
+			"FAIL: default validator, empty ID",
+			" ",
+			nil,
+			fmt.Errorf("revocation list ID is empty"),
+		},
+		{
+			"PASS: url under the namespace",
+			"https://example.com/credentials/status/3",
+			[]Option{WithIDValidator(namespace)},
+			nil,
+		},
+		{
+			"FAIL: url outside the namespace",
+			"https://example.org/credentials/status/3",
+			[]Option{WithIDValidator(namespace)},
+			fmt.Errorf("revocation list ID https://example.org/credentials/status/3 is not under https://example.com"),
+		},
+		{
+			"FAIL: not an https url",
+			"c0",
+			[]Option{WithIDValidator(namespace)},
+			fmt.Errorf("revocation list ID c0 is not under https://example.com"),
+		},
+		{
+			"FAIL: nil validator",
+			"c0",
+			[]Option{WithIDValidator(nil)},
+			fmt.Errorf("ID validator must not be nil"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList(tt.id, 16)
+			data, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(data, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.id, rlN.ID)
+		})
+	}
+}
+
+func TestWithMinimumSize(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		kbSize  int
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: default minimum",
+			32,
+			nil,
+			nil,
+		},
+		{
+			"PASS: at the minimum",
+			64,
+			[]Option{WithMinimumSize(64)},
+			nil,
+		},
+		{
+			"FAIL: below the minimum",
+			32,
+			[]Option{WithMinimumSize(64)},
+			fmt.Errorf("size out of bounds: must be at least 64, got 32"),
+		},
+		{
+			"FAIL: above the maximum",
+			129,
+			[]Option{WithMinimumSize(64)},
+			fmt.Errorf("size out of bounds: must be between 16 and 128, got 129"),
+		},
+		{
+			"FAIL: minimum above the maximum",
+			128,
+			[]Option{WithMinimumSize(256)},
+			fmt.Errorf("size out of bounds: must be between 16 and 128, got 256"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", tt.kbSize, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.ErrorIs(t, err, ErrSizeOutOfBounds)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kbSize, rl.Size())
+		})
+	}
+
+	// the minimum applies to resizing and lists with an exact capacity too
+	rl, _ := NewRevocationList("c0", 64, WithMinimumSize(64))
+	assert.ErrorIs(t, rl.Resize(32), ErrSizeOutOfBounds)
+	rl, err := NewRevocationListWithBits("c0", 100000, WithMinimumSize(64))
+	assert.NoError(t, err)
+	assert.Equal(t, 64, rl.Size())
+	assert.Equal(t, 100000, rl.Capacity())
+}
+
+func TestWithObserver(t *testing.T) {
+	type event struct {
+		action bool
+		count  int
+	}
+	var events []event
+	rl, _ := NewRevocationList("c0", 16, WithObserver(func(action bool, count int) {
+		events = append(events, event{action, count})
+	}))
+
+	assert.NoError(t, rl.Revoke(1, 2, 3, 3))
+	assert.NoError(t, rl.Revoke(3, 4))
+	assert.NoError(t, rl.Reset(1, 10))
+	assert.Error(t, rl.Revoke(131072))
+	assert.Error(t, rl.TryUpdate(Revoke, 5, 131072))
+	assert.NoError(t, rl.UpdateSorted(Reset, 2, 3, 4, 5))
+	assert.NoError(t, rl.RevokeRange(0, 8))
+	snapshot := rl.Snapshot()
+	assert.NoError(t, rl.Toggle(7, 8, 9))
+	assert.NoError(t, rl.Toggle(7, 8))
+	assert.NoError(t, rl.RestoreSnapshot(snapshot))
+	other, _ := NewRevocationListWithRevoked("c0", 16, []int{1, 20, 21})
+	assert.NoError(t, rl.Merge(&other))
+	assert.NoError(t, rl.ResetAll())
+	assert.NoError(t, rl.RevokeAll())
+
+	assert.Equal(t, []event{
+		{Revoke, 3},
+		{Revoke, 1},
+		{Reset, 1},
+		{Revoke, 1},
+		{Reset, 4},
+		{Revoke, 8},
+		{Revoke, 2},
+		{Reset, 1},
+		{Revoke, 1},
+		{Reset, 1},
+		{Reset, 1},
+		{Revoke, 2},
+		{Reset, 10},
+		{Revoke, 131072},
+	}, events)
+
+	// a list without observer behaves the same
+	plain, _ := NewRevocationList("c0", 16, WithObserver(nil))
+	assert.NoError(t, plain.Revoke(1, 2, 3))
+	assert.NoError(t, plain.RevokeRange(0, 8))
+	assert.NoError(t, plain.Toggle(7, 8, 9))
+	assert.NoError(t, plain.RestoreSnapshot(snapshot))
+	assert.NoError(t, plain.Merge(&other))
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 20, 21}, plain.RevokedIndexes())
+	assert.NoError(t, plain.RevokeAll())
+	assert.True(t, rl.Equal(&plain))
+}
+
+func TestWithMaxRevocations(t *testing.T) {
+	rl, err := NewRevocationList("c0", 16, WithMaxRevocations(3))
+	assert.NoError(t, err)
+
+	// revoke up to the quota, revoking twice the same credential is free
+	assert.NoError(t, rl.Revoke(1, 2))
+	assert.NoError(t, rl.Revoke(2, 3))
+	err = rl.Revoke(4)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.EqualError(t, err, "revocation quota exceeded, at most 3 credentials can be revoked, got 4")
+	assert.Equal(t, []int{1, 2, 3}, rl.RevokedIndexes())
+	assert.False(t, rl.IsDirty())
+
+	// every update that revokes credentials is subject to the quota
+	assert.ErrorIs(t, rl.RevokeRange(10, 20), ErrQuotaExceeded)
+	assert.ErrorIs(t, rl.UpdateSorted(Revoke, 10, 11), ErrQuotaExceeded)
+	assert.ErrorIs(t, rl.TryUpdate(Revoke, 10, 131072), ErrQuotaExceeded)
+	assert.ErrorIs(t, rl.Toggle(1, 10, 11), ErrQuotaExceeded)
+	assert.ErrorIs(t, rl.RevokeAll(), ErrQuotaExceeded)
+	assert.Equal(t, []int{1, 2, 3}, rl.RevokedIndexes())
+
+	// resetting frees the quota
+	assert.NoError(t, rl.Reset(1))
+	assert.NoError(t, rl.Revoke(4))
+	assert.NoError(t, rl.Toggle(2, 5))
+	assert.Equal(t, []int{3, 4, 5}, rl.RevokedIndexes())
+
+	_, err = NewRevocationList("c0", 16, WithMaxRevocations(0))
+	assert.EqualError(t, err, "max revocations must be positive, got 0")
+}
+
+func TestPack_Deterministic(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		compression Compression
+		want        string
+	}{
+		{
+			"PASS: zlib",
+			Zlib,
+			"eJzsxjEBAAAEADAkF90lg8N2reJQbgAAAAAAAAAAAAAAAAAAAOC1ngEAABIAhA==",
+		},
+		{
+			"PASS: gzip",
+			Gzip,
+			"H4sIAAAAAAAA/+zGMQEAAAQAMCQX3SWDw3at4lBuAAAAAAAAAAAAAAAAAAAA4LWeAQB7y4NSAEAAAA==",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				rl, _ := NewRevocationList("c0", 16, WithCompression(tt.compression))
+				assert.NoError(t, rl.Revoke(1, 1000, 131071))
+				assert.Equal(t, tt.want, rl.EncodedList)
+			}
+		})
+	}
+
+	// the gzip header has no modification time and an unknown OS
+	rl, _ := NewRevocationList("c0", 16, WithCompression(Gzip))
+	b, _ := base64.StdEncoding.DecodeString(rl.EncodedList)
+	assert.Equal(t, []byte{0, 0, 0, 0}, b[4:8])
+	assert.Equal(t, byte(0xff), b[9])
+}
+
+func TestWithMaxSize(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		kbSize  int
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: raised max size",
+			512,
+			[]Option{WithMaxSize(1024)},
+			nil,
+		},
+		{
+			"FAIL: beyond the raised max size",
+			1025,
+			[]Option{WithMaxSize(1024)},
+			fmt.Errorf("size out of bounds: must be between 16 and 1024, got 1025"),
+		},
+		{
+			"FAIL: beyond the lowered max size",
+			64,
+			[]Option{WithMaxSize(32)},
+			fmt.Errorf("size out of bounds: must be between 16 and 32, got 64"),
+		},
+		{
+			"FAIL: below the absolute minimum",
+			8,
+			[]Option{WithMaxSize(1024)},
+			fmt.Errorf("size out of bounds: must be between 16 and 1024, got 8"),
+		},
+		{
+			"FAIL: max size below the absolute minimum",
+			16,
+			[]Option{WithMaxSize(8)},
+			fmt.Errorf("size out of bounds: max size must be at least 16, got 8"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", tt.kbSize, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kbSize, rl.Size())
+			assert.NoError(t, rl.Revoke(rl.Capacity()-1))
+
+			// parsing the list requires the same max size
+			data, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(data, tt.opts...)
+			assert.NoError(t, err)
+			assert.True(t, rl.Equal(&rlN))
+			_, err = NewRevocationListFromJSON(data)
+			assert.ErrorIs(t, err, ErrSizeOutOfBounds)
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16, WithCompression(Gzip))
+	assert.NoError(t, rl.Revoke(1))
+	data, _ := rl.GetBytes()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, err := NewRevocationListFromJSON(data, WithLogger(logger))
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `level=DEBUG msg="revocation list decoded"`)
+	assert.Contains(t, buf.String(), "compression=gzip size=16384")
+
+	buf.Reset()
+	_, err = NewRevocationListFromJSON([]byte(`{"id":"c0","type":"RevocationList2020","encodedList":"!!"}`), WithLogger(logger))
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `msg="decoding revocation list failed" encodedLength=2`)
+
+	// nothing is logged above the debug level
+	buf.Reset()
+	logger = slog.New(slog.NewTextHandler(&buf, nil))
+	_, err = NewRevocationListFromJSON(data, WithLogger(logger))
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestWithIDNormalizer(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		listID   string
+		statusID string
+		opts     []Option
+		wantErr  error
+	}{
+		{"PASS: trailing slash", "https://example.com/credentials/status/3", "https://example.com/credentials/status/3/", []Option{WithIDNormalizer(NormalizeID)}, nil},
+		{"PASS: trailing slash in the list", "https://example.com/credentials/status/3/", "https://example.com/credentials/status/3", []Option{WithIDNormalizer(NormalizeID)}, nil},
+		{"PASS: host case", "https://example.com/credentials/status/3", "HTTPS://Example.COM/credentials/status/3", []Option{WithIDNormalizer(NormalizeID)}, nil},
+		{"PASS: custom normalizer", "c0", "C0", []Option{WithIDNormalizer(strings.ToLower)}, nil},
+		{"FAIL: path case", "https://example.com/credentials/status/3", "https://example.com/Credentials/status/3", []Option{WithIDNormalizer(NormalizeID)}, ErrWrongList},
+		{"FAIL: strict by default", "https://example.com/credentials/status/3", "https://example.com/credentials/status/3/", nil, ErrWrongList},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList(tt.listID, 16, tt.opts...)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(10))
+			cs := NewCredentialStatus(tt.statusID, 10)
+			isIt, err := rl.IsRevoked(cs)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.False(t, rl.Owns(cs))
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+			assert.True(t, rl.Owns(cs))
+		})
+	}
+
+	_, err := NewRevocationList("c0", 16, WithIDNormalizer(nil))
+	assert.EqualError(t, err, "ID normalizer must not be nil")
+}
+
+func TestWithCompactEmpty(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 128, WithCompactEmpty())
+	data, err := json.Marshal(&rl)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"c0","type":"RevocationList2020","encodedList":"empty:128","schemaVersion":1}`, string(data))
+
+	got, err := NewRevocationListFromJSON(data, WithCompactEmpty())
+	assert.NoError(t, err)
+	assert.Equal(t, 128*1024*8, got.Capacity())
+	assert.True(t, rl.Equal(&got))
+	assert.False(t, got.IsDirty())
+	assert.Equal(t, rl.EncodedList, got.EncodedList)
+
+	// the marker is expanded without the option too, also by json.Unmarshal
+	got, err = NewRevocationListFromJSON(data)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&got))
+	var unmarshalled RevocationList2020
+	assert.NoError(t, json.Unmarshal(data, &unmarshalled))
+	assert.True(t, rl.Equal(&unmarshalled))
+	assert.Equal(t, rl.EncodedList, unmarshalled.EncodedList)
+
+	// lists with revocations are encoded as usual
+	assert.NoError(t, got.Revoke(10))
+	data, _ = json.Marshal(&got)
+	assert.NotContains(t, string(data), "empty:")
+	got, err = NewRevocationListFromJSON(data, WithCompactEmpty())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10}, got.RevokedIndexes())
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"FAIL: invalid marker", `{"id":"c0","type":"RevocationList2020","encodedList":"empty:lots"}`, fmt.Errorf("revocation list c0: corrupt encoded list: invalid empty list marker empty:lots")},
+		{"FAIL: marker size too big", `{"id":"c0","type":"RevocationList2020","encodedList":"empty:1000000000"}`, fmt.Errorf("size out of bounds: must be between 16 and 128, got 1000000000")},
+		{"FAIL: marker size too small", `{"id":"c0","type":"RevocationList2020","encodedList":"empty:-1"}`, fmt.Errorf("size out of bounds: must be between 16 and 128, got -1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRevocationListFromJSON([]byte(tt.data))
+			assert.EqualError(t, err, tt.wantErr.Error())
+		})
+	}
+}
+
+func TestWithRevision(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16, WithRevision())
+	assert.Zero(t, rl.Revision())
+
+	assert.NoError(t, rl.Revoke(1, 2))
+	assert.Equal(t, uint64(1), rl.Revision())
+	assert.NoError(t, rl.Reset(1))
+	assert.Equal(t, uint64(2), rl.Revision())
+	assert.NoError(t, rl.RevokeRange(10, 20))
+	assert.NoError(t, rl.Toggle(3))
+	assert.Equal(t, uint64(4), rl.Revision())
+	// failed updates do not change the revision
+	assert.Error(t, rl.Revoke(131072))
+	assert.Equal(t, uint64(4), rl.Revision())
+
+	// the revision is preserved across serialization
+	data, err := json.Marshal(&rl)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"revision":4`)
+	got, err := NewRevocationListFromJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), got.Revision())
+	assert.NoError(t, got.Revoke(5))
+	assert.Equal(t, uint64(5), got.Revision())
+	assert.Equal(t, uint64(4), rl.Revision())
+
+	// without the option the revision is not serialized
+	data, _ = json.Marshal(&got)
+	assert.NotContains(t, string(data), "revision")
+}
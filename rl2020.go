@@ -2,22 +2,66 @@ package rl2020
 
 import (
 	"bytes"
+	"compress/gzip"
 	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
 )
 
 const (
-	maxBitSetSize                    = 128 // max size is 128kb
-	minBitSetSize                    = 16  // minimum bit set size
+	maxBitSetSize                    = 128                      // default max size is 128kb
+	minBitSetSize                    = 16                       // minimum bit set size
+	maxAllocSize                     = math.MaxInt / (8 * 1024) // largest size in kb whose capacity fits an int
+	packChunkSize                    = 8 * 1024                 // chunk size checked for cancellation when packing
+	compactEmptyPrefix               = "empty:"                 // followed by the size in kb of an empty list, see WithCompactEmpty
 	TypeRevocationList2020           = "RevocationList2020"
 	TypeRevocationList2020Credential = "RevocationList2020Credential"
-	TypeRevocationList2020Status     = "RevocationList2020status"
+	TypeRevocationList2020Status     = "RevocationList2020Status"
 	Revoke                           = true
 	Reset                            = false
+	PurposeRevocation                = "revocation" // a set bit means the credential is permanently revoked
+	PurposeSuspension                = "suspension" // a set bit means the credential is temporarily suspended
+	// SchemaVersion is the version of the serializations using extensions to the specification,
+	// such as WithCompactEmpty and WithRevision, lists with a newer version are rejected
+	SchemaVersion = 1
+	// legacyTypeRevocationList2020Status is the misspelled status type used by previous
+	// versions of this package, it is still accepted when checking a credential status
+	legacyTypeRevocationList2020Status = "RevocationList2020status"
+)
+
+// Sentinel errors wrapped by the errors returned from this package,
+// use errors.Is to check for them
+var (
+	ErrIndexOutOfRange    = errors.New("credential index out of range")
+	ErrWrongList          = errors.New("wrong revocation list")
+	ErrUnsupportedType    = errors.New("unsupported type")
+	ErrEmptyID            = errors.New("ID is empty")
+	ErrSizeOutOfBounds    = errors.New("size out of bounds")
+	ErrExpired            = errors.New("revocation list expired")
+	ErrCorruptEncodedList = errors.New("corrupt encoded list")
+	ErrQuotaExceeded      = errors.New("revocation quota exceeded")
+	ErrUnsupportedSchema  = errors.New("unsupported schema version")
+	ErrSealed             = errors.New("revocation list sealed")
 )
 
 // CredentialStatus represent the status block of a credential issued using the RevocationList2020
@@ -37,10 +81,24 @@ type CredentialStatusJSON struct {
 	Type                     string `json:"type"`
 	RevocationListIndex      int    `json:"revocationListIndex"`
 	RevocationListCredential string `json:"revocationListCredential"`
+	StatusPurpose            string `json:"statusPurpose,omitempty"`
+}
+
+var _ CredentialStatus = CredentialStatusJSON{}
+
+// purposer is implemented by the credential statuses that carry a status purpose
+type purposer interface {
+	Purpose() string
 }
 
-// Coordinates retun the revocation list id and credential index within the list
+// Coordinates retun the revocation list id and credential index within the list,
+// for legacy statuses without the revocation list credential they are parsed from the ID
 func (cs CredentialStatusJSON) Coordinates() (string, int) {
+	if cs.RevocationListCredential == "" {
+		if list, index, err := ParseCredentialStatusID(cs.ID); err == nil {
+			return list, index
+		}
+	}
 	return cs.RevocationListCredential, cs.RevocationListIndex
 }
 
@@ -49,199 +107,2313 @@ func (cs CredentialStatusJSON) TypeDef() (string, string) {
 	return cs.ID, cs.Type
 }
 
-// NewCredentialStatus creates a new CredentialStatus
+// Purpose returns the status purpose of the credential status, a status
+// without a purpose is a revocation status
+func (cs CredentialStatusJSON) Purpose() string {
+	if cs.StatusPurpose == "" {
+		return PurposeRevocation
+	}
+	return cs.StatusPurpose
+}
+
+// ParseCredentialStatus parses the json serialized credentialStatus of a credential,
+// that can be either a single status or an array of statuses
+func ParseCredentialStatus(data []byte) (statuses []CredentialStatus, err error) {
+	var list []CredentialStatusJSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &list)
+	} else {
+		list = make([]CredentialStatusJSON, 1)
+		err = json.Unmarshal(trimmed, &list[0])
+	}
+	if err != nil {
+		return
+	}
+	statuses = make([]CredentialStatus, len(list))
+	for i, cs := range list {
+		statuses[i] = cs
+	}
+	return
+}
+
+// NewCredentialStatus creates a new CredentialStatus, the concrete type returned
+// is a CredentialStatusJSON that can be used directly with IsRevoked
 func NewCredentialStatus(rlCredential string, rlIndex int) CredentialStatus {
 	return CredentialStatusJSON{
-		ID:                       fmt.Sprint(rlCredential, "/", rlIndex),
+		ID:                       credentialStatusID(rlCredential, rlIndex),
 		Type:                     TypeRevocationList2020Status,
 		RevocationListCredential: rlCredential,
 		RevocationListIndex:      rlIndex,
 	}
 }
 
+// ValidateCredentialStatus checks that a CredentialStatus has the expected type and
+// that its ID is consistent with its coordinates, that is, in the form "list/index"
+func ValidateCredentialStatus(cs CredentialStatus) error {
+	csID, csType := cs.TypeDef()
+	if !isStatusType(csType) {
+		return fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, csType, TypeRevocationList2020Status)
+	}
+	if expected := credentialStatusID(cs.Coordinates()); csID != expected {
+		return fmt.Errorf("inconsistent credential status ID, expected %v, got %v", expected, csID)
+	}
+	return nil
+}
+
+// isStatusType reports whether t is the RevocationList2020Status type, including its legacy spelling
+func isStatusType(t string) bool {
+	return t == TypeRevocationList2020Status || t == legacyTypeRevocationList2020Status
+}
+
+// ParseCredentialStatusID splits the ID of a credential status in the form "list/index"
+// into the revocation list ID and the credential index
+func ParseCredentialStatusID(id string) (list string, index int, err error) {
+	i := strings.LastIndex(id, "/")
+	if i <= 0 {
+		err = fmt.Errorf("malformed credential status ID %v, expected list/index", id)
+		return
+	}
+	if index, err = strconv.Atoi(id[i+1:]); err != nil || index < 0 {
+		err = fmt.Errorf("malformed credential status ID %v, the index must be a non negative integer", id)
+		return "", 0, err
+	}
+	list = id[:i]
+	return
+}
+
+// credentialStatusID builds the ID of a credential status from its coordinates
+func credentialStatusID(rlCredential string, rlIndex int) string {
+	return fmt.Sprint(rlCredential, "/", rlIndex)
+}
+
 // RevocationList2020 represent the credential subject of a RevocationList2020 credential as
 // defined in https://w3c-ccg.github.io/vc-status-rl-2020/
+//
+// A RevocationList2020 created with one of the constructors is safe for concurrent use.
+// The methods have pointer receivers, since copying a list while another goroutine updates it
+// is a data race: share lists by pointer, e.g. map[string]*RevocationList2020. Only String and
+// the marshalling methods have value receivers, so that lists passed by value serialize the same.
 type RevocationList2020 struct {
-	ID          string `json:"id"`
-	Type        string `json:"type"`
-	EncodedList string `json:"encodedList"`
-	bitSet      bitSet `json:"-"`
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Purpose      string    `json:"statusPurpose,omitempty"`
+	Issuer       string    `json:"issuer,omitempty"`
+	IssuanceDate time.Time `json:"issuanceDate"` // omitted when zero
+	ValidFrom    time.Time `json:"validFrom"`    // omitted when zero
+	ValidUntil   time.Time `json:"validUntil"`   // omitted when zero
+	EncodedList  string    `json:"encodedList"`
+	// Extras holds the properties of the list that are not defined by the specification,
+	// they are captured when parsing and emitted after the standard ones when serializing
+	Extras    map[string]json.RawMessage `json:"-"`
+	bitSet    bitSet
+	bits      int    // logical capacity, zero means the whole bit set
+	allocated bitSet // indexes returned by AllocateRandom
+	revision  uint64 // number of updates of the list
+	sealed    bool   // set by Seal
+	opts      options
+	mu        *sync.RWMutex
 }
 
 // NewRevocationList creates a new revocation lists of the specified size
-func NewRevocationList(id string, kbSize int) (rl RevocationList2020, err error) {
-	if kbSize > maxBitSetSize || kbSize < minBitSetSize {
-		err = fmt.Errorf("size must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, kbSize)
+func NewRevocationList(id string, kbSize int, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	if err = o.checkSize(kbSize); err != nil {
+		return
+	}
+	if err = o.checkMinimumSize(kbSize); err != nil {
+		return
+	}
+	bs, err := newBitSet(kbSize)
+	if err != nil {
 		return
 	}
-	bs := newBitSet(kbSize)
-	ebs, err := pack(bs)
+	ebs, err := pack(bs, o)
 	if err != nil {
 		return
 	}
 	rl = RevocationList2020{
 		ID:          id,
 		Type:        TypeRevocationList2020,
+		Purpose:     o.purpose,
 		EncodedList: ebs,
 		bitSet:      bs,
+		opts:        o,
+		mu:          new(sync.RWMutex),
 	}
 	return
 }
 
-// NewRevocationListFromJSON parse
-func NewRevocationListFromJSON(data []byte) (rl RevocationList2020, err error) {
-	if err = json.Unmarshal(data, &rl); err != nil {
+// NewRevocationListWithRevoked creates a new revocation list of the specified size with the
+// credentials at the revoked indexes already revoked, the list is packed once
+func NewRevocationListWithRevoked(id string, kbSize int, revoked []int, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
 		return
 	}
-	if strings.TrimSpace(rl.ID) == "" {
-		err = fmt.Errorf("revocation list has no ID")
+	if err = o.checkSize(kbSize); err != nil {
 		return
 	}
-	if rl.Type != TypeRevocationList2020 {
-		err = fmt.Errorf("unsupported type %v, expected %v", rl.Type, TypeRevocationList2020)
+	if err = o.checkMinimumSize(kbSize); err != nil {
 		return
 	}
-	// decode the revocation list to a bit set
-	if rl.bitSet, err = unpack(rl.EncodedList); err != nil {
+	bs, err := newBitSet(kbSize)
+	if err != nil {
 		return
 	}
-	// check the bitset size
-	if rl.Size() > maxBitSetSize || rl.Size() < minBitSetSize {
-		err = fmt.Errorf("size must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, rl.Size())
+	for _, ci := range revoked {
+		if err = bs.trySetBit(ci, Revoke); err != nil {
+			return
+		}
+	}
+	ebs, err := pack(bs, o)
+	if err != nil {
 		return
 	}
+	rl = RevocationList2020{
+		ID:          id,
+		Type:        TypeRevocationList2020,
+		Purpose:     o.purpose,
+		EncodedList: ebs,
+		bitSet:      bs,
+		opts:        o,
+		mu:          new(sync.RWMutex),
+	}
 	return
 }
 
-// Capacity returns the number of credentials that can be handled by this revocation list
-func (rl RevocationList2020) Capacity() int {
-	return rl.bitSet.len()
-}
-
-// Size returns the size in KB of the revocation list
-func (rl RevocationList2020) Size() int {
-	return rl.bitSet.size()
+// NewRevocationListWithIssuer is like NewRevocationList but also records who
+// published the list and when
+func NewRevocationListWithIssuer(id string, kbSize int, issuer string, issuanceDate time.Time, opts ...Option) (rl RevocationList2020, err error) {
+	if rl, err = NewRevocationList(id, kbSize, opts...); err != nil {
+		return
+	}
+	rl.Issuer, rl.IssuanceDate = issuer, issuanceDate.UTC()
+	return
 }
 
-// Update - set a list of credential indexes either to revoked (action to true) or reset (action to false)
-func (rl *RevocationList2020) Update(action bool, indexes ...int) (err error) {
-	for _, i := range indexes {
-		if i < 0 || i >= rl.Capacity() {
-			err = fmt.Errorf("credential index out of range 0-%d: %v", rl.Capacity(), i)
+// NewRevocationListWithBits creates a new revocation list with a capacity of exactly bits credentials,
+// indexes beyond the capacity are rejected. The bit set is rounded up to a whole number of KB,
+// and it is never smaller than the minimum size allowed for a list. The capacity is serialized
+// as an extension to the specification, implementations that ignore it see the whole bit set
+func NewRevocationListWithBits(id string, bits int, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	if bits < 1 || bits > o.maxSize*8*1024 {
+		err = fmt.Errorf("%w: must be between %d and %d bits, got %d", ErrSizeOutOfBounds, 1, o.maxSize*8*1024, bits)
+		return
+	}
+	if rl, err = NewRevocationList(id, o.minimumSize, opts...); err != nil {
+		return
+	}
+	if kbSize := (bits-1)/(8*1024) + 1; kbSize > rl.bitSet.size() {
+		if rl.bitSet, err = newBitSet(kbSize); err != nil {
+			return
+		}
+		if rl.EncodedList, err = pack(rl.bitSet, rl.opts); err != nil {
 			return
 		}
 	}
-	for _, ci := range indexes {
-		rl.bitSet.setBit(ci, action)
+	rl.bits = bits
+	return
+}
+
+// NewRevocationListFromBools creates a new revocation list from the status of each credential,
+// the length of bits must be a whole number of KB within the allowed size bounds
+func NewRevocationListFromBools(id string, bits []bool, opts ...Option) (rl RevocationList2020, err error) {
+	if len(bits)%(8*1024) != 0 {
+		err = fmt.Errorf("number of credentials must be a multiple of %d, got %d", 8*1024, len(bits))
+		return
+	}
+	if rl, err = NewRevocationList(id, len(bits)/(8*1024), opts...); err != nil {
+		return
 	}
-	rl.EncodedList, err = pack(rl.bitSet)
+	for i, b := range bits {
+		rl.bitSet.setBit(i, b)
+	}
+	rl.EncodedList, err = pack(rl.bitSet, rl.opts)
 	return
 }
 
-// BitSet return the bitset associated with the revocation list
-func (rl RevocationList2020) BitSet() []byte {
-	return rl.bitSet
+// NewRevocationListFromHex creates a new revocation list from the hex encoding of its bit set,
+// as returned by ToHex
+func NewRevocationListFromHex(id string, s string, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if err = o.checkBitSet(bs); err != nil {
+		return
+	}
+	if err = o.checkMinimumSize(bitSet(bs).size()); err != nil {
+		return
+	}
+	ebs, err := pack(bs, o)
+	if err != nil {
+		return
+	}
+	rl = RevocationList2020{
+		ID:          id,
+		Type:        TypeRevocationList2020,
+		Purpose:     o.purpose,
+		EncodedList: ebs,
+		bitSet:      bs,
+		opts:        o,
+		mu:          new(sync.RWMutex),
+	}
+	return
 }
 
-// Revoke revoke a credential by it's index, that is, set the corresponding bit to 1
-func (rl *RevocationList2020) Revoke(credentials ...int) (err error) {
-	return rl.Update(Revoke, credentials...)
+// NewRevocationListFromJSON parse a json serialized revocation list, the encoding
+// of the list is detected automatically
+func NewRevocationListFromJSON(data []byte, opts ...Option) (rl RevocationList2020, err error) {
+	return NewRevocationListFromJSONContext(context.Background(), data, opts...)
 }
 
-// Reset reset a credential status by it's index, that is, set the corresponding bit to 0
-func (rl *RevocationList2020) Reset(credentials ...int) (err error) {
-	return rl.Update(Reset, credentials...)
+// NewRevocationListFromJSONContext is like NewRevocationListFromJSON but aborts
+// unpacking the list when ctx is done
+func NewRevocationListFromJSONContext(ctx context.Context, data []byte, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	err = rl.unmarshalJSON(ctx, data, o)
+	return
 }
 
-// IsRevoked check the value for CredentialStatus in the list. Check if the corresponding
-// bit is set (1) or not (0)
-func (rl RevocationList2020) IsRevoked(status CredentialStatus) (isIt bool, err error) {
-	csID, csType := status.TypeDef()
-	if strings.TrimSpace(csID) == "" {
-		err = fmt.Errorf("credential status ID is empty")
+// NewRevocationListFromReader parse a json serialized revocation list reading it from r,
+// to guard against oversized payloads it reads at most twice the decompression limit
+func NewRevocationListFromReader(r io.Reader, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
 		return
 	}
-	if csType != TypeRevocationList2020Status {
-		err = fmt.Errorf("unsupported type %v, expected %v", rl.Type, TypeRevocationList2020Status)
+	var data json.RawMessage
+	if err = json.NewDecoder(newLimitedReader(r, 2*o.readLimit())).Decode(&data); err != nil {
 		return
 	}
-	// check corordinates
-	list, index := status.Coordinates()
-	if list != rl.ID {
-		err = fmt.Errorf("wrong revocation list, expected %v, got %v", rl.ID, list)
+	err = rl.unmarshalJSON(context.Background(), data, o)
+	return
+}
+
+// UnmarshalJSON parses a json serialized revocation list using the default options,
+// validating it and decoding its bit set the same way NewRevocationListFromJSON does
+func (rl *RevocationList2020) UnmarshalJSON(data []byte) error {
+	o, err := newOptions()
+	if err != nil {
+		return err
+	}
+	return rl.unmarshalJSON(context.Background(), data, o)
+}
+
+func (rl *RevocationList2020) unmarshalJSON(ctx context.Context, data []byte, o options) (err error) {
+	// the alias prevents UnmarshalJSON from calling itself
+	type revocationList RevocationList2020
+	var raw struct {
+		revocationList
+		// the type may also be an array, as in the credentials
+		Type          json.RawMessage `json:"type"`
+		Revision      uint64          `json:"revision"`
+		Capacity      int             `json:"capacity"`
+		SchemaVersion int             `json:"schemaVersion"`
+	}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	v := raw.revocationList
+	v.revision, v.bits = raw.Revision, raw.Capacity
+	if err = checkSchemaVersion(raw.SchemaVersion); err != nil {
+		return
+	}
+	if err = o.validateID(v.ID); err != nil {
+		return
+	}
+	if v.Type, err = parseListType(raw.Type); err != nil {
 		return
 	}
-	if index < 0 || index >= rl.Capacity() {
-		err = fmt.Errorf("credential index out of range 0-%d: %v", rl.Capacity(), list)
+	if v.Extras, err = parseExtras(data); err != nil {
 		return
 	}
+	list := RevocationList2020(v)
+	if err = list.decodeList(ctx, o); err != nil {
+		return
+	}
+	return rl.replace(list)
+}
 
-	isIt = rl.bitSet.getBit(index)
+// decodeList validates the purpose of a parsed list and decodes its encoded list,
+// the ID and the type must have been validated already
+func (rl *RevocationList2020) decodeList(ctx context.Context, o options) (err error) {
+	// lists without a purpose are revocation lists
+	if rl.Purpose == "" {
+		rl.Purpose = PurposeRevocation
+	}
+	if err = checkPurpose(rl.Purpose); err != nil {
+		return
+	}
+	// decode the revocation list to a bit set
+	if strings.HasPrefix(rl.EncodedList, compactEmptyPrefix) {
+		if rl.bitSet, rl.EncodedList, err = expandEmpty(rl.EncodedList, o); err != nil {
+			err = withListID(rl.ID, err)
+			return
+		}
+	} else if rl.bitSet, err = unpackContext(ctx, rl.EncodedList, &o); err != nil {
+		err = withListID(rl.ID, err)
+		return
+	}
+	// check the bitset size
+	if err = o.checkBitSet(rl.bitSet); err != nil {
+		return
+	}
+	if rl.bits < 0 || rl.bits > len(rl.bitSet)*8 {
+		err = fmt.Errorf("%w: capacity must be between 1 and %d, got %d", ErrSizeOutOfBounds, len(rl.bitSet)*8, rl.bits)
+		return
+	}
+	// the indexes beyond the capacity cannot be addressed, so they cannot be revoked
+	for i := rl.capacity(); i < rl.bitSet.len(); i++ {
+		if rl.bitSet.getBit(i) {
+			err = withListID(rl.ID, fmt.Errorf("%w: index %d is revoked beyond the capacity %d", ErrCorruptEncodedList, i, rl.bits))
+			return
+		}
+	}
+	rl.opts = o
+	rl.mu = new(sync.RWMutex)
 	return
 }
 
-// GetBytes returns the json serialized revocation list
-func (rl RevocationList2020) GetBytes() ([]byte, error) {
-	return json.Marshal(rl)
+// serializedFields are the properties of a serialized list, see serializedList
+var serializedFields = map[string]bool{
+	"id": true, "type": true, "statusPurpose": true, "issuer": true, "issuanceDate": true,
+	"validFrom": true, "validUntil": true, "encodedList": true, "revision": true, "capacity": true, "schemaVersion": true,
 }
 
-type bitSet []uint8
-
-func newBitSet(kbSize int) (bs bitSet) {
-	return make([]uint8, kbSize*1024)
+// checkSchemaVersion rejects the lists serialized with a newer SchemaVersion,
+// whose extensions may not be understood
+func checkSchemaVersion(v int) error {
+	if v > SchemaVersion {
+		return fmt.Errorf("%w %d, expected at most %d", ErrUnsupportedSchema, v, SchemaVersion)
+	}
+	return nil
 }
 
-func (bs bitSet) getBit(index int) bool {
-	pos := index / 8
-	j := index % 8
-	return (bs[pos] & (uint8(1) << j)) != 0
-}
-func (bs bitSet) setBit(index int, value bool) {
-	pos := index / 8
-	j := uint(index % 8)
-	if value {
-		bs[pos] |= uint8(1) << j
-	} else {
-		bs[pos] &= ^(uint8(1) << j)
+// parseExtras returns the properties of a serialized list that are not serializedFields,
+// or nil if there are none
+func parseExtras(data []byte) (extras map[string]json.RawMessage, err error) {
+	if err = json.Unmarshal(data, &extras); err != nil {
+		return
+	}
+	for k := range extras {
+		if serializedFields[k] {
+			delete(extras, k)
+		}
 	}
+	if len(extras) == 0 {
+		extras = nil
+	}
+	return
 }
 
-func (bs bitSet) len() int {
-	return 8 * len(bs)
+// expandEmpty allocates the empty list described by a WithCompactEmpty marker,
+// returning its bit set and encoded list
+func expandEmpty(marker string, o options) (bs bitSet, ebs string, err error) {
+	kbSize, err := strconv.Atoi(strings.TrimPrefix(marker, compactEmptyPrefix))
+	if err != nil {
+		err = fmt.Errorf("%w: invalid empty list marker %v", ErrCorruptEncodedList, marker)
+		return
+	}
+	// check the size before allocating the list
+	if err = o.checkSize(kbSize); err != nil {
+		return
+	}
+	if bs, err = newBitSet(kbSize); err != nil {
+		return
+	}
+	ebs, err = pack(bs, o)
+	return
 }
 
-// size returns the size of the bitset int kb
-func (bs bitSet) size() int {
-	return len(bs) / 1024
+// parseListType reads the type of a list, either a string or an array of strings,
+// returning TypeRevocationList2020 if it is the type or one of the types of the list
+func parseListType(raw json.RawMessage) (t string, err error) {
+	var types []string
+	if err = json.Unmarshal(raw, &t); err != nil {
+		if err = json.Unmarshal(raw, &types); err != nil {
+			return "", fmt.Errorf("%w %s, expected %v", ErrUnsupportedType, raw, TypeRevocationList2020)
+		}
+		for _, t = range types {
+			if t == TypeRevocationList2020 {
+				return
+			}
+		}
+		return "", fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, types, TypeRevocationList2020)
+	}
+	if t != TypeRevocationList2020 {
+		return "", fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, t, TypeRevocationList2020)
+	}
+	return
 }
 
-func pack(set bitSet) (s string, err error) {
-	var bb bytes.Buffer
-	// fist compress the data
-	w := zlib.NewWriter(&bb)
-	if _, err = w.Write(set); err != nil {
+// ValidateEncodedList checks that an encoded list decodes to a bit set within the allowed
+// size bounds, without building a revocation list, and returns its size in KB
+func ValidateEncodedList(s string, opts ...Option) (kbSize int, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
 		return
 	}
-	if err = w.Close(); err != nil {
+	bs, err := unpack(s, &o)
+	if err != nil {
 		return
 	}
-	// encode to base64
-	s = base64.StdEncoding.EncodeToString(bb.Bytes())
+	if err = o.checkBitSet(bs); err != nil {
+		return
+	}
+	kbSize = bs.size()
 	return
 }
 
-func unpack(s string) (bs bitSet, err error) {
-	b, err := base64.StdEncoding.DecodeString(s)
-	if err != nil {
-		return
+// checkPurpose verifies that the status purpose of a list is supported
+func checkPurpose(purpose string) error {
+	if purpose != PurposeRevocation && purpose != PurposeSuspension {
+		return fmt.Errorf("unsupported status purpose %v, expected %v or %v", purpose, PurposeRevocation, PurposeSuspension)
 	}
-	// pass the buffer to the zlib reader
-	zr, err := zlib.NewReader(bytes.NewReader(b))
+	return nil
+}
+
+// KBSizeForCapacity returns the smallest size in KB to pass to NewRevocationList
+// for the list to handle at least n credentials, opts can change the minimum and maximum size
+func KBSizeForCapacity(n int, opts ...Option) (kbSize int, err error) {
+	o, err := newOptions(opts...)
 	if err != nil {
 		return
 	}
-	if err = zr.Close(); err != nil {
+	if n < 0 || n > o.maxSize*8*1024 {
+		err = fmt.Errorf("%w: capacity must be between 0 and %d, got %d", ErrSizeOutOfBounds, o.maxSize*8*1024, n)
 		return
 	}
-	return io.ReadAll(zr)
+	if kbSize = (n + 8*1024 - 1) / (8 * 1024); kbSize < o.minimumSize {
+		kbSize = o.minimumSize
+	}
+	return
+}
+
+// rLock acquires the read lock of the list and returns the function to release it
+func (rl *RevocationList2020) rLock() (unlock func()) {
+	if rl.mu == nil {
+		return func() {}
+	}
+	rl.mu.RLock()
+	return rl.mu.RUnlock
+}
+
+// lock acquires the write lock of the list and returns the function to release it
+func (rl *RevocationList2020) lock() (unlock func()) {
+	if rl.mu == nil {
+		return func() {}
+	}
+	rl.mu.Lock()
+	return rl.mu.Unlock
+}
+
+// Capacity returns the number of credentials that can be handled by this revocation list
+func (rl *RevocationList2020) Capacity() int {
+	defer rl.rLock()()
+	return rl.capacity()
+}
+
+// capacity returns the logical capacity of the list, the caller must hold the lock
+func (rl *RevocationList2020) capacity() int {
+	if rl.bits > 0 {
+		return rl.bits
+	}
+	return rl.bitSet.len()
+}
+
+// checkIndex returns an error if index is outside of the list capacity, the caller must hold the lock
+func (rl *RevocationList2020) checkIndex(index int) error {
+	if index < 0 || index >= rl.capacity() {
+		return fmt.Errorf("%w 0-%d: %v", ErrIndexOutOfRange, rl.capacity(), index)
+	}
+	return nil
+}
+
+// ExpectCapacity returns an error if the capacity of the list is not n, for example
+// to detect that a list of a different size was loaded after a configuration change
+func (rl *RevocationList2020) ExpectCapacity(n int) error {
+	defer rl.rLock()()
+	if c := rl.capacity(); c != n {
+		return fmt.Errorf("revocation list %v has a capacity of %d credentials, expected %d", rl.ID, c, n)
+	}
+	return nil
+}
+
+// Size returns the size in KB of the revocation list
+func (rl *RevocationList2020) Size() int {
+	defer rl.rLock()()
+	return rl.bitSet.size()
+}
+
+// RevokedCount returns the number of credentials currently revoked in the list
+func (rl *RevocationList2020) RevokedCount() int {
+	defer rl.rLock()()
+	return rl.bitSet.count()
+}
+
+// HasRevocations reports whether any credential in the list is revoked,
+// it stops at the first revoked credential so it is cheaper than RevokedCount
+func (rl *RevocationList2020) HasRevocations() bool {
+	defer rl.rLock()()
+	for _, b := range rl.bitSet {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CapacityRemaining returns the number of credentials that are not revoked,
+// it is the same as Stats().Available
+func (rl *RevocationList2020) CapacityRemaining() int {
+	defer rl.rLock()()
+	return rl.capacity() - rl.bitSet.count()
+}
+
+// ToBoolSlice returns the status of each credential in the list, true if revoked
+func (rl *RevocationList2020) ToBoolSlice() []bool {
+	defer rl.rLock()()
+	bits := make([]bool, rl.capacity())
+	for i := range bits {
+		bits[i] = rl.bitSet.getBit(i)
+	}
+	return bits
+}
+
+// Stats summarizes the usage of a revocation list
+type Stats struct {
+	Capacity  int
+	Revoked   int
+	Available int
+	FillRatio float64
+}
+
+// Stats returns the capacity, the revoked and available credentials count and
+// the fill ratio of the revocation list
+func (rl *RevocationList2020) Stats() (s Stats) {
+	defer rl.rLock()()
+	s.Capacity = rl.capacity()
+	s.Revoked = rl.bitSet.count()
+	s.Available = s.Capacity - s.Revoked
+	if s.Capacity > 0 {
+		s.FillRatio = float64(s.Revoked) / float64(s.Capacity)
+	}
+	return
+}
+
+// Summary is a machine readable description of a revocation list
+type Summary struct {
+	ID        string  `json:"id"`
+	Type      string  `json:"type"`
+	Capacity  int     `json:"capacity"`
+	Revoked   int     `json:"revoked"`
+	SizeKB    int     `json:"sizeKB"`
+	FillRatio float64 `json:"fillRatio"`
+}
+
+// Summary returns a summary of the revocation list that can be serialized to JSON,
+// String returns the same information for humans
+func (rl *RevocationList2020) Summary() (s Summary) {
+	defer rl.rLock()()
+	s.ID, s.Type = rl.ID, rl.Type
+	s.Capacity = rl.capacity()
+	s.Revoked = rl.bitSet.count()
+	s.SizeKB = rl.bitSet.size()
+	if s.Capacity > 0 {
+		s.FillRatio = float64(s.Revoked) / float64(s.Capacity)
+	}
+	return
+}
+
+// RevokedIndexes returns the indexes of all the revoked credentials in ascending order
+func (rl *RevocationList2020) RevokedIndexes() (indexes []int) {
+	defer rl.rLock()()
+	indexes = make([]int, 0, rl.bitSet.count())
+	rl.bitSet.forEach(func(index int) bool {
+		indexes = append(indexes, index)
+		return true
+	})
+	return
+}
+
+// RevokedRanges returns the revoked credentials as [start, end) ranges of contiguous
+// indexes in ascending order
+func (rl *RevocationList2020) RevokedRanges() (ranges [][2]int) {
+	defer rl.rLock()()
+	ranges = [][2]int{}
+	rl.bitSet.forEach(func(index int) bool {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == index {
+			ranges[n-1][1]++
+		} else {
+			ranges = append(ranges, [2]int{index, index + 1})
+		}
+		return true
+	})
+	return
+}
+
+// ForEachRevoked calls fn for each revoked credential index in ascending order,
+// the iteration stops as soon as fn returns false. The list is locked for reading
+// during the iteration, so fn must not modify it
+func (rl *RevocationList2020) ForEachRevoked(fn func(index int) bool) {
+	defer rl.rLock()()
+	rl.bitSet.forEach(fn)
+}
+
+// FindFirstAvailable returns the lowest credential index that is not revoked,
+// it returns an error if the list is full
+func (rl *RevocationList2020) FindFirstAvailable() (int, error) {
+	return rl.FindFirstAvailableFrom(0)
+}
+
+// FindFirstAvailableFrom returns the lowest credential index, greater or equal to start,
+// that is not revoked. It returns an error if there are no available indexes after start
+func (rl *RevocationList2020) FindFirstAvailableFrom(start int) (index int, err error) {
+	defer rl.rLock()()
+	if err = rl.checkIndex(start); err != nil {
+		return
+	}
+	if index = rl.bitSet.firstZero(start); index < 0 || index >= rl.capacity() {
+		index = -1
+		err = fmt.Errorf("no available credential index from %d", start)
+	}
+	return
+}
+
+// AllocateRandom returns a uniformly random credential index that is neither revoked nor already
+// returned by AllocateRandom, so that credentials issued close together do not get correlated
+// indexes. It returns an error if there are no available indexes
+func (rl *RevocationList2020) AllocateRandom() (index int, err error) {
+	defer rl.lock()()
+	rl.resizeAllocated()
+	// count the available indexes, the bits beyond the capacity are never available
+	available := 0
+	for pos := range rl.bitSet {
+		available += bits.OnesCount8(rl.availableMask(pos))
+	}
+	if available == 0 {
+		err = fmt.Errorf("no available credential index")
+		return
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(available)))
+	if err != nil {
+		return
+	}
+	// find the n-th available index, skipping whole bytes when possible
+	k := int(n.Int64())
+	for pos := range rl.bitSet {
+		mask := rl.availableMask(pos)
+		if c := bits.OnesCount8(mask); k >= c {
+			k -= c
+			continue
+		}
+		for j := 0; j < 8; j++ {
+			if mask&(uint8(1)<<j) == 0 {
+				continue
+			}
+			if k == 0 {
+				index = pos*8 + j
+				rl.allocated.setBit(index, true)
+				return
+			}
+			k--
+		}
+	}
+	return
+}
+
+// IssueStatus allocates the lowest credential index that is neither revoked nor already allocated,
+// without revoking it, and returns the CredentialStatus pointing at it. The allocated indexes are
+// shared with AllocateRandom. It returns an error if there are no available indexes
+func (rl *RevocationList2020) IssueStatus() (cs CredentialStatus, err error) {
+	defer rl.lock()()
+	rl.resizeAllocated()
+	for pos := range rl.bitSet {
+		if mask := rl.availableMask(pos); mask != 0 {
+			index := pos*8 + bits.TrailingZeros8(mask)
+			rl.allocated.setBit(index, true)
+			cs = rl.newStatus(index)
+			return
+		}
+	}
+	err = fmt.Errorf("no available credential index")
+	return
+}
+
+// StatusFor returns the CredentialStatus pointing at the credential index in the list, with the
+// purpose of the list unless it is revocation. It returns an error if the index is out of range
+func (rl *RevocationList2020) StatusFor(index int) (cs CredentialStatus, err error) {
+	defer rl.rLock()()
+	if err = rl.checkIndex(index); err != nil {
+		return
+	}
+	cs = rl.newStatus(index)
+	return
+}
+
+// newStatus returns the CredentialStatus of index, carrying the purpose of the list
+// unless it is the implied revocation purpose. The caller must hold the lock
+func (rl *RevocationList2020) newStatus(index int) CredentialStatus {
+	cs := NewCredentialStatus(rl.ID, index).(CredentialStatusJSON)
+	if p := rl.purpose(); p != PurposeRevocation {
+		cs.StatusPurpose = p
+	}
+	return cs
+}
+
+// resizeAllocated makes the allocated indexes as long as the bit set, the caller must hold the lock
+func (rl *RevocationList2020) resizeAllocated() {
+	if len(rl.allocated) != len(rl.bitSet) {
+		allocated := make(bitSet, len(rl.bitSet))
+		copy(allocated, rl.allocated)
+		rl.allocated = allocated
+	}
+}
+
+// availableMask returns the bits of the byte at pos that are available for allocation,
+// the caller must hold the lock
+func (rl *RevocationList2020) availableMask(pos int) uint8 {
+	end := rl.capacity() - pos*8
+	if end <= 0 {
+		return 0
+	}
+	mask := ^(rl.bitSet[pos] | rl.allocated[pos])
+	if end < 8 {
+		mask &= uint8(1)<<end - 1
+	}
+	return mask
+}
+
+// Update - set a list of credential indexes either to revoked (action to true) or reset (action to false)
+func (rl *RevocationList2020) Update(action bool, indexes ...int) (err error) {
+	return rl.UpdateContext(context.Background(), action, indexes...)
+}
+
+// UpdateContext is like Update but aborts packing the list when ctx is done,
+// in which case the list is left unchanged
+func (rl *RevocationList2020) UpdateContext(ctx context.Context, action bool, indexes ...int) (err error) {
+	_, err = rl.update(ctx, action, indexes)
+	return
+}
+
+// UpdateChanged is like Update but also returns the number of credentials whose status
+// actually changed, that is zero if all the credentials were already in the target state
+func (rl *RevocationList2020) UpdateChanged(action bool, indexes ...int) (changed int, err error) {
+	return rl.update(context.Background(), action, indexes)
+}
+
+// update sets the credential indexes to action and returns the number of bits that changed
+func (rl *RevocationList2020) update(ctx context.Context, action bool, indexes []int) (changed int, err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	for _, i := range indexes {
+		if err = rl.checkIndex(i); err != nil {
+			return
+		}
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	previous := make([]bool, len(indexes))
+	for i, ci := range indexes {
+		// a repeated index is already in the target state the second time
+		if previous[i] = rl.bitSet.getBit(ci); previous[i] != action {
+			changed++
+		}
+		rl.bitSet.setBit(ci, action)
+	}
+	if err = checkQuota(); err != nil {
+		return 0, err
+	}
+	ebs, err := packContext(ctx, rl.bitSet, rl.opts)
+	if err != nil {
+		// restore in reverse order to account for repeated indexes
+		for i := len(indexes) - 1; i >= 0; i-- {
+			rl.bitSet.setBit(indexes[i], previous[i])
+		}
+		return 0, err
+	}
+	rl.EncodedList = ebs
+	rl.revision++
+	rl.observe(action, before)
+	return
+}
+
+// observedCount returns the number of revoked credentials if the list has an observer,
+// to be passed to observe once the update is done. The caller must hold the lock
+func (rl *RevocationList2020) observedCount() int {
+	if rl.opts.observer == nil {
+		return 0
+	}
+	return rl.bitSet.count()
+}
+
+// observe notifies the observer, if any, of the number of credentials changed by an update,
+// given the number of revoked credentials before it. The caller must hold the lock
+func (rl *RevocationList2020) observe(action bool, before int) {
+	if rl.opts.observer == nil {
+		return
+	}
+	n := rl.bitSet.count() - before
+	if n < 0 {
+		n = -n
+	}
+	rl.opts.observer(action, n)
+}
+
+// observedBitSet returns a copy of the bit set if the list has an observer, to be passed
+// to observeChanges once the update is done. The caller must hold the lock
+func (rl *RevocationList2020) observedBitSet() bitSet {
+	if rl.opts.observer == nil {
+		return nil
+	}
+	return bytes.Clone(rl.bitSet)
+}
+
+// observeChanges notifies the observer, if any, of the number of credentials revoked and
+// reset since previous, with a call for each action that changed at least one credential.
+// The caller must hold the lock
+func (rl *RevocationList2020) observeChanges(previous bitSet) {
+	if rl.opts.observer == nil {
+		return
+	}
+	var revoked, reset int
+	for i, b := range rl.bitSet {
+		revoked += bits.OnesCount8(b &^ previous[i])
+		reset += bits.OnesCount8(previous[i] &^ b)
+	}
+	if revoked > 0 {
+		rl.opts.observer(Revoke, revoked)
+	}
+	if reset > 0 {
+		rl.opts.observer(Reset, reset)
+	}
+}
+
+// quotaGuard returns a function to call once the bit set has been updated, that fails and restores
+// the previous bit set if the update revoked more credentials than allowed by WithMaxRevocations.
+// The caller must hold the lock
+func (rl *RevocationList2020) quotaGuard() (check func() error) {
+	if rl.opts.maxRevocations <= 0 {
+		return func() error { return nil }
+	}
+	previous := append(bitSet(nil), rl.bitSet...)
+	before := previous.count()
+	return func() error {
+		// a list over the quota can still reset credentials
+		if n := rl.bitSet.count(); n > rl.opts.maxRevocations && n > before {
+			copy(rl.bitSet, previous)
+			return fmt.Errorf("%w, at most %d credentials can be revoked, got %d", ErrQuotaExceeded, rl.opts.maxRevocations, n)
+		}
+		return nil
+	}
+}
+
+// Toggle flips the status of the credentials at indexes, revoking the ones that are not revoked
+// and resetting the ones that are. A repeated index is flipped once for each occurrence
+func (rl *RevocationList2020) Toggle(indexes ...int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	for _, i := range indexes {
+		if err = rl.checkIndex(i); err != nil {
+			return
+		}
+	}
+	checkQuota := rl.quotaGuard()
+	previous := rl.observedBitSet()
+	for _, ci := range indexes {
+		rl.bitSet.flipBit(ci)
+	}
+	if err = checkQuota(); err != nil {
+		return
+	}
+	ebs, err := pack(rl.bitSet, rl.opts)
+	if err != nil {
+		// flipping again restores the previous state
+		for _, ci := range indexes {
+			rl.bitSet.flipBit(ci)
+		}
+		return
+	}
+	rl.EncodedList = ebs
+	rl.revision++
+	rl.observeChanges(previous)
+	return
+}
+
+// TryUpdate is like Update but applies the valid indexes even if some are out of range,
+// the returned error joins the errors for all the invalid indexes
+func (rl *RevocationList2020) TryUpdate(action bool, indexes ...int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	var errs []error
+	for _, ci := range indexes {
+		if e := rl.checkIndex(ci); e != nil {
+			errs = append(errs, e)
+			continue
+		}
+		rl.bitSet.setBit(ci, action)
+	}
+	if err = checkQuota(); err != nil {
+		return
+	}
+	ebs, packErr := pack(rl.bitSet, rl.opts)
+	if packErr != nil {
+		errs = append(errs, packErr)
+	} else {
+		rl.EncodedList = ebs
+		rl.revision++
+		rl.observe(action, before)
+	}
+	return errors.Join(errs...)
+}
+
+// UpdateSorted is like Update but requires the indexes to be sorted in ascending order,
+// which allows to validate only the boundaries and to update the bit set a byte at a time
+func (rl *RevocationList2020) UpdateSorted(action bool, indexes ...int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if len(indexes) == 0 {
+		return
+	}
+	for i := 1; i < len(indexes); i++ {
+		if indexes[i] < indexes[i-1] {
+			err = fmt.Errorf("credential indexes must be sorted in ascending order")
+			return
+		}
+	}
+	if err = rl.checkIndex(indexes[0]); err != nil {
+		return
+	}
+	if err = rl.checkIndex(indexes[len(indexes)-1]); err != nil {
+		return
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	// accumulate the bits of each byte and write them at once
+	pos, mask := indexes[0]/8, uint8(0)
+	for _, ci := range indexes {
+		if ci/8 != pos {
+			rl.bitSet.setMask(pos, mask, action)
+			pos, mask = ci/8, 0
+		}
+		mask |= uint8(1) << (ci % 8)
+	}
+	rl.bitSet.setMask(pos, mask, action)
+	if err = checkQuota(); err != nil {
+		return
+	}
+	if rl.EncodedList, err = pack(rl.bitSet, rl.opts); err == nil {
+		rl.revision++
+		rl.observe(action, before)
+	}
+	return
+}
+
+// UpdateRange - set the credential indexes in the range [start, end) either to revoked (action to true)
+// or reset (action to false)
+func (rl *RevocationList2020) UpdateRange(action bool, start, end int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if start < 0 || start > end || end > rl.capacity() {
+		err = fmt.Errorf("%w 0-%d: [%d, %d)", ErrIndexOutOfRange, rl.capacity(), start, end)
+		return
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	for ci := start; ci < end; ci++ {
+		rl.bitSet.setBit(ci, action)
+	}
+	if err = checkQuota(); err != nil {
+		return
+	}
+	if rl.EncodedList, err = pack(rl.bitSet, rl.opts); err == nil {
+		rl.revision++
+		rl.observe(action, before)
+	}
+	return
+}
+
+// RevokeRange revoke the credentials with index in the range [start, end)
+func (rl *RevocationList2020) RevokeRange(start, end int) (err error) {
+	return rl.UpdateRange(Revoke, start, end)
+}
+
+// ResetRange reset the credentials with index in the range [start, end)
+func (rl *RevocationList2020) ResetRange(start, end int) (err error) {
+	return rl.UpdateRange(Reset, start, end)
+}
+
+// RevokeAll revoke all the credentials in the list
+func (rl *RevocationList2020) RevokeAll() error {
+	return rl.fill(0xff)
+}
+
+// ResetAll reset all the credentials in the list
+func (rl *RevocationList2020) ResetAll() error {
+	return rl.fill(0x00)
+}
+
+// fill sets all the bytes of the bit set to b and re-packs the list
+func (rl *RevocationList2020) fill(b uint8) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	for i := range rl.bitSet {
+		rl.bitSet[i] = b
+	}
+	// keep the bits beyond the capacity cleared
+	for i := rl.capacity(); i < rl.bitSet.len(); i++ {
+		rl.bitSet.setBit(i, false)
+	}
+	if err = checkQuota(); err != nil {
+		return
+	}
+	if rl.EncodedList, err = pack(rl.bitSet, rl.opts); err == nil {
+		rl.revision++
+		rl.observe(b != 0, before)
+	}
+	return
+}
+
+// Merge revokes all the credentials revoked in other, the two lists must have the same ID and capacity
+func (rl *RevocationList2020) Merge(other *RevocationList2020) (err error) {
+	// other is copied before locking rl, the locks of the two lists are never held together
+	v := other.view()
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if err = rl.checkCompatible(v); err != nil {
+		return
+	}
+	checkQuota := rl.quotaGuard()
+	before := rl.observedCount()
+	for i, b := range v.bitSet {
+		rl.bitSet[i] |= b
+	}
+	if err = checkQuota(); err != nil {
+		return
+	}
+	if rl.EncodedList, err = pack(rl.bitSet, rl.opts); err == nil {
+		rl.revision++
+		rl.observe(Revoke, before)
+	}
+	return
+}
+
+// Diff compares the list with a previous version of it, returning the indexes of the
+// credentials that have been revoked and reset since then
+func (rl *RevocationList2020) Diff(previous *RevocationList2020) (revoked []int, reset []int, err error) {
+	v := previous.view()
+	defer rl.rLock()()
+	if err = rl.checkCompatible(v); err != nil {
+		return
+	}
+	for pos, b := range rl.bitSet {
+		// skip the bytes that did not change
+		changed := b ^ v.bitSet[pos]
+		if changed == 0 {
+			continue
+		}
+		for j := 0; j < 8; j++ {
+			if changed&(uint8(1)<<j) == 0 {
+				continue
+			}
+			if b&(uint8(1)<<j) != 0 {
+				revoked = append(revoked, pos*8+j)
+			} else {
+				reset = append(reset, pos*8+j)
+			}
+		}
+	}
+	return
+}
+
+// listView is a copy of the fields of a list compared by Merge, Diff and Equal
+type listView struct {
+	id       string
+	typ      string
+	capacity int
+	bitSet   bitSet
+}
+
+// view copies the fields of the list compared with another list, holding the read lock
+func (rl *RevocationList2020) view() listView {
+	defer rl.rLock()()
+	return listView{id: rl.ID, typ: rl.Type, capacity: rl.capacity(), bitSet: bytes.Clone(rl.bitSet)}
+}
+
+// checkCompatible verifies that other has the same ID and capacity of the list,
+// the caller must hold the lock
+func (rl *RevocationList2020) checkCompatible(other listView) error {
+	if other.id != rl.ID {
+		return fmt.Errorf("%w, expected %v, got %v", ErrWrongList, rl.ID, other.id)
+	}
+	if other.capacity != rl.capacity() || len(other.bitSet) != len(rl.bitSet) {
+		return fmt.Errorf("revocation list capacity mismatch, expected %d, got %d", rl.capacity(), other.capacity)
+	}
+	return nil
+}
+
+// Resize changes the size in KB of the revocation list preserving the existing revocations.
+// Shrinking the list fails if any of the dropped credential indexes is revoked, the capacity
+// of the resized list is always a whole number of KB
+func (rl *RevocationList2020) Resize(kbSize int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	return rl.resize(kbSize)
+}
+
+// Extend appends kbBlocks KB of available credential indexes at the end of the list,
+// the existing indexes are unchanged
+func (rl *RevocationList2020) Extend(kbBlocks int) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if kbBlocks < 1 {
+		err = fmt.Errorf("cannot extend the list by %dkb", kbBlocks)
+		return
+	}
+	return rl.resize(rl.bitSet.size() + kbBlocks)
+}
+
+// ShrinkToFit drops the trailing KB of the list that have no revoked credentials,
+// the list is never shrunk below the minimum size
+func (rl *RevocationList2020) ShrinkToFit() (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	last := len(rl.bitSet) - 1
+	for last >= 0 && rl.bitSet[last] == 0 {
+		last--
+	}
+	kbSize := (last + 1 + 1023) / 1024
+	if kbSize < rl.opts.minimumSize {
+		kbSize = rl.opts.minimumSize
+	}
+	if kbSize >= rl.bitSet.size() {
+		return
+	}
+	return rl.resize(kbSize)
+}
+
+// resize changes the size of the list, the caller must hold the lock
+func (rl *RevocationList2020) resize(kbSize int) (err error) {
+	if err = rl.opts.checkSize(kbSize); err != nil {
+		return
+	}
+	if err = rl.opts.checkMinimumSize(kbSize); err != nil {
+		return
+	}
+	bs, err := newBitSet(kbSize)
+	if err != nil {
+		return
+	}
+	// check that no revoked credential falls out of the new list
+	if len(bs) < len(rl.bitSet) {
+		for _, b := range rl.bitSet[len(bs):] {
+			if b != 0 {
+				err = fmt.Errorf("cannot resize to %dkb, the list has revoked credentials beyond index %d", kbSize, bs.len()-1)
+				return
+			}
+		}
+	}
+	copy(bs, rl.bitSet)
+	ebs, err := pack(bs, rl.opts)
+	if err != nil {
+		return
+	}
+	rl.bitSet, rl.EncodedList, rl.bits = bs, ebs, 0
+	rl.revision++
+	return
+}
+
+// IsDirty reports whether the encoded list is stale, that is it does not decode to the current
+// bit set, which happens when the bits are modified without re-packing the list
+func (rl *RevocationList2020) IsDirty() bool {
+	defer rl.rLock()()
+	o := rl.opts
+	bs, err := unpack(rl.EncodedList, &o)
+	return err != nil || !bytes.Equal(bs, rl.bitSet)
+}
+
+// Sync re-packs the bit set updating the encoded list
+func (rl *RevocationList2020) Sync() (err error) {
+	defer rl.lock()()
+	rl.EncodedList, err = pack(rl.bitSet, rl.opts)
+	return
+}
+
+// IsExpired reports whether now falls outside the validity window of the list,
+// a zero ValidFrom or ValidUntil leaves the window open on that side
+func (rl *RevocationList2020) IsExpired(now time.Time) bool {
+	defer rl.rLock()()
+	return (!rl.ValidFrom.IsZero() && now.Before(rl.ValidFrom)) ||
+		(!rl.ValidUntil.IsZero() && !now.Before(rl.ValidUntil))
+}
+
+// Snapshot returns a copy of the bit set of the list, to restore it later with RestoreSnapshot
+func (rl *RevocationList2020) Snapshot() []byte {
+	defer rl.rLock()()
+	return append([]byte(nil), rl.bitSet...)
+}
+
+// RestoreSnapshot restores the bit set of the list from a snapshot returned by Snapshot,
+// the snapshot must have the same size of the list
+func (rl *RevocationList2020) RestoreSnapshot(b []byte) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if len(b) != len(rl.bitSet) {
+		err = fmt.Errorf("snapshot size mismatch, expected %d bytes, got %d", len(rl.bitSet), len(b))
+		return
+	}
+	bs := append(bitSet(nil), b...)
+	ebs, err := pack(bs, rl.opts)
+	if err != nil {
+		return
+	}
+	previous := rl.bitSet
+	rl.bitSet, rl.EncodedList = bs, ebs
+	rl.revision++
+	rl.observeChanges(previous)
+	return
+}
+
+// Clone returns a deep copy of the revocation list that can be modified
+// without affecting the original one
+func (rl *RevocationList2020) Clone() RevocationList2020 {
+	defer rl.rLock()()
+	bs := make(bitSet, len(rl.bitSet))
+	copy(bs, rl.bitSet)
+	c := *rl
+	c.bitSet = bs
+	c.allocated = append(bitSet(nil), rl.allocated...)
+	c.Extras = maps.Clone(rl.Extras)
+	c.sealed = false
+	c.mu = new(sync.RWMutex)
+	return c
+}
+
+// Seal makes the list read-only, for example once it is published, the updates of a sealed
+// list fail with ErrSealed. Clone returns a copy of the list that is not sealed
+func (rl *RevocationList2020) Seal() {
+	defer rl.lock()()
+	rl.sealed = true
+}
+
+// IsSealed reports whether the list has been sealed
+func (rl *RevocationList2020) IsSealed() bool {
+	defer rl.rLock()()
+	return rl.sealed
+}
+
+// checkSealed returns an error if the list is sealed, the caller must hold the lock
+func (rl *RevocationList2020) checkSealed() error {
+	if rl.sealed {
+		return fmt.Errorf("%w, %v cannot be modified", ErrSealed, rl.ID)
+	}
+	return nil
+}
+
+// replace overwrites rl with a decoded list, keeping the mutex of rl so that the
+// goroutines sharing it stay synchronized. A sealed list cannot be replaced
+func (rl *RevocationList2020) replace(list RevocationList2020) (err error) {
+	defer rl.lock()()
+	if err = rl.checkSealed(); err != nil {
+		return
+	}
+	if rl.mu != nil {
+		list.mu = rl.mu
+	}
+	*rl = list
+	return
+}
+
+// Revision returns the number of successful updates of the list, it can be compared
+// with a previous revision to find out whether the list changed
+func (rl *RevocationList2020) Revision() uint64 {
+	defer rl.rLock()()
+	return rl.revision
+}
+
+// Equal reports whether two revocation lists have the same ID, type and revocations,
+// the encoded lists are not compared since they may differ in encoding or compression
+func (rl *RevocationList2020) Equal(other *RevocationList2020) bool {
+	v := other.view()
+	defer rl.rLock()()
+	return rl.ID == v.id && rl.Type == v.typ && rl.capacity() == v.capacity &&
+		bytes.Equal(rl.bitSet, v.bitSet)
+}
+
+// ContentHash returns the SHA-256 hash of the list ID and of its bit set, it does not depend
+// on the encoding or compression of the list so it can be used as an ETag
+func (rl *RevocationList2020) ContentHash() (sum [32]byte) {
+	defer rl.rLock()()
+	h := sha256.New()
+	// prefix the ID with its length so that ID and bit set cannot be confused
+	_ = binary.Write(h, binary.BigEndian, uint64(len(rl.ID)))
+	h.Write([]byte(rl.ID))
+	h.Write(rl.bitSet)
+	copy(sum[:], h.Sum(nil))
+	return
+}
+
+// BitSet returns a copy of the bitset associated with the revocation list
+func (rl *RevocationList2020) BitSet() []byte {
+	defer rl.rLock()()
+	return bytes.Clone(rl.bitSet)
+}
+
+// ToHex returns the hex encoding of the raw bit set, independent of the encoding
+// and compression of the encoded list, useful to debug or reproduce issues
+func (rl *RevocationList2020) ToHex() string {
+	defer rl.rLock()()
+	return hex.EncodeToString(rl.bitSet)
+}
+
+// Revoke revoke a credential by it's index, that is, set the corresponding bit to 1
+func (rl *RevocationList2020) Revoke(credentials ...int) (err error) {
+	return rl.Update(Revoke, credentials...)
+}
+
+// Reset reset a credential status by it's index, that is, set the corresponding bit to 0
+func (rl *RevocationList2020) Reset(credentials ...int) (err error) {
+	return rl.Update(Reset, credentials...)
+}
+
+// RevokeStatus revokes the credential of a CredentialStatus, the status must belong to the list
+func (rl *RevocationList2020) RevokeStatus(status CredentialStatus) error {
+	return rl.updateStatus(Revoke, status)
+}
+
+// ResetStatus resets the credential of a CredentialStatus, the status must belong to the list
+func (rl *RevocationList2020) ResetStatus(status CredentialStatus) error {
+	return rl.updateStatus(Reset, status)
+}
+
+// updateStatus updates the credential of a status after checking it belongs to the list
+func (rl *RevocationList2020) updateStatus(action bool, status CredentialStatus) error {
+	unlock := rl.rLock()
+	index, err := rl.indexOf(status)
+	unlock()
+	if err != nil {
+		return err
+	}
+	return rl.Update(action, index)
+}
+
+// IsRevoked check the value for CredentialStatus in the list. Check if the corresponding
+// bit is set (1) or not (0)
+func (rl *RevocationList2020) IsRevoked(status CredentialStatus) (isIt bool, err error) {
+	defer rl.rLock()()
+	index, err := rl.indexOf(status)
+	if err != nil {
+		return
+	}
+	isIt = rl.bitSet.getBit(index)
+	return
+}
+
+// IsRevokedByIndex checks whether the credential at index is revoked, it returns
+// an error if the index is out of range
+func (rl *RevocationList2020) IsRevokedByIndex(index int) (isIt bool, err error) {
+	defer rl.rLock()()
+	if err = rl.checkIndex(index); err != nil {
+		return
+	}
+	isIt = rl.bitSet.getBit(index)
+	return
+}
+
+// Status is the status of a credential, derived from its bit and the purpose of the list
+type Status int
+
+const (
+	// StatusActive credentials are neither revoked nor suspended
+	StatusActive Status = iota
+	// StatusRevoked credentials are set in a list with the revocation purpose
+	StatusRevoked
+	// StatusSuspended credentials are set in a list with the suspension purpose
+	StatusSuspended
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusActive:
+		return "active"
+	case StatusRevoked:
+		return "revoked"
+	case StatusSuspended:
+		return "suspended"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// StatusOf is like IsRevoked but returns the status of the credential according
+// to the purpose of the list
+func (rl *RevocationList2020) StatusOf(status CredentialStatus) (s Status, err error) {
+	defer rl.rLock()()
+	index, err := rl.indexOf(status)
+	if err != nil || !rl.bitSet.getBit(index) {
+		return
+	}
+	if rl.purpose() == PurposeSuspension {
+		return StatusSuspended, nil
+	}
+	return StatusRevoked, nil
+}
+
+// Owns reports whether status points at this list and within its capacity, without reading
+// the bit of the credential, to route a status to the right list
+func (rl *RevocationList2020) Owns(status CredentialStatus) bool {
+	defer rl.rLock()()
+	list, index := status.Coordinates()
+	return rl.opts.sameID(list, rl.ID) && index >= 0 && index < rl.capacity()
+}
+
+// IsRevokedConstantTime is like IsRevoked but reads the bit of the credential without branching
+// on its value, so that the time taken does not reveal whether the credential is revoked
+func (rl *RevocationList2020) IsRevokedConstantTime(status CredentialStatus) (isIt bool, err error) {
+	defer rl.rLock()()
+	index, err := rl.indexOf(status)
+	if err != nil {
+		return
+	}
+	isIt = rl.bitSet.getBitConstantTime(index)
+	return
+}
+
+// IsRevokedAny checks the statuses of a credential carrying multiple status entries, only the
+// entries pointing at this list and with the same status purpose are checked. It reports whether
+// any of them is set, and returns an error if none of the entries matches the list
+func (rl *RevocationList2020) IsRevokedAny(statuses []CredentialStatus) (isIt bool, err error) {
+	defer rl.rLock()()
+	matched := false
+	for _, status := range statuses {
+		if list, _ := status.Coordinates(); !rl.opts.sameID(list, rl.ID) {
+			continue
+		}
+		if p, ok := status.(purposer); ok && p.Purpose() != rl.purpose() {
+			continue
+		}
+		index, err := rl.indexOf(status)
+		if err != nil {
+			return false, err
+		}
+		matched = true
+		isIt = isIt || rl.bitSet.getBit(index)
+	}
+	if !matched {
+		err = fmt.Errorf("%w, no credential status for %v with purpose %v", ErrWrongList, rl.ID, rl.purpose())
+	}
+	return
+}
+
+// purpose returns the status purpose of the list, the caller must hold the lock
+func (rl *RevocationList2020) purpose() string {
+	if rl.Purpose == "" {
+		return PurposeRevocation
+	}
+	return rl.Purpose
+}
+
+// AreRevoked checks a batch of CredentialStatus against the list, returning the revocation
+// flags in the same order of the statuses. It fails on the first status that is not valid
+// for the list, reporting its position in the batch
+func (rl *RevocationList2020) AreRevoked(statuses []CredentialStatus) (revoked []bool, err error) {
+	defer rl.rLock()()
+	revoked = make([]bool, len(statuses))
+	for i, status := range statuses {
+		index, err := rl.indexOf(status)
+		if err != nil {
+			return nil, fmt.Errorf("credential status %d: %w", i, err)
+		}
+		revoked[i] = rl.bitSet.getBit(index)
+	}
+	return
+}
+
+// indexOf validates a CredentialStatus against the list and returns its index, the
+// caller must hold the lock
+func (rl *RevocationList2020) indexOf(status CredentialStatus) (index int, err error) {
+	csID, csType := status.TypeDef()
+	if strings.TrimSpace(csID) == "" {
+		err = fmt.Errorf("credential status %w", ErrEmptyID)
+		return
+	}
+	if !isStatusType(csType) {
+		err = fmt.Errorf("%w %v, expected %v", ErrUnsupportedType, csType, TypeRevocationList2020Status)
+		return
+	}
+	// check corordinates
+	list, index := status.Coordinates()
+	if !rl.opts.sameID(list, rl.ID) {
+		err = fmt.Errorf("%w, expected %v, got %v", ErrWrongList, rl.ID, list)
+		return
+	}
+	err = rl.checkIndex(index)
+	return
+}
+
+// String returns a concise description of the revocation list, without the encoded list
+func (rl RevocationList2020) String() string {
+	defer rl.rLock()()
+	return fmt.Sprintf("%s(id=%s, capacity=%d, revoked=%d, size=%dKB)",
+		rl.Type, rl.ID, rl.capacity(), rl.bitSet.count(), rl.bitSet.size())
+}
+
+// GetBytes returns the json serialized revocation list
+func (rl *RevocationList2020) GetBytes() ([]byte, error) {
+	defer rl.rLock()()
+	return rl.marshalJSON()
+}
+
+// WriteTo writes the json serialized revocation list to w, it implements io.WriterTo
+func (rl *RevocationList2020) WriteTo(w io.Writer) (int64, error) {
+	defer rl.rLock()()
+	data, err := rl.marshalJSON()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// binaryVersion is the version of the format written by MarshalBinary
+const binaryVersion = 1
+
+// MarshalBinary serializes the list in a compact binary form: a version byte, the ID, purpose,
+// issuer and dates of the list as strings prefixed by their length as a uvarint, the revision
+// and the capacity as uvarints, the compression byte and finally the compressed bit set.
+// Extras are not serialized
+func (rl RevocationList2020) MarshalBinary() (data []byte, err error) {
+	defer rl.rLock()()
+	b := []byte{binaryVersion}
+	for _, v := range []string{rl.ID, rl.Purpose, rl.Issuer, formatTime(rl.IssuanceDate), formatTime(rl.ValidFrom), formatTime(rl.ValidUntil)} {
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		b = append(b, v...)
+	}
+	b = binary.AppendUvarint(b, rl.revision)
+	b = binary.AppendUvarint(b, uint64(rl.bits))
+	bb := bytes.NewBuffer(append(b, byte(rl.opts.compression)))
+	if err = compressContext(context.Background(), bb, rl.bitSet, rl.opts); err != nil {
+		return
+	}
+	data = bb.Bytes()
+	return
+}
+
+// errMalformedBinary is returned by UnmarshalBinary for truncated data
+var errMalformedBinary = errors.New("malformed binary revocation list")
+
+// binaryReader reads the fields written by MarshalBinary, keeping the first error
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, l := binary.Uvarint(r.data)
+	if l <= 0 {
+		r.err = errMalformedBinary
+		return 0
+	}
+	r.data = r.data[l:]
+	return v
+}
+
+func (r *binaryReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errMalformedBinary
+		return ""
+	}
+	v := string(r.data[:n])
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) time() (t time.Time) {
+	if v := r.string(); v != "" && r.err == nil {
+		t, r.err = time.Parse(time.RFC3339, v)
+	}
+	return
+}
+
+// UnmarshalBinary restores a revocation list serialized with MarshalBinary
+func (rl *RevocationList2020) UnmarshalBinary(data []byte) (err error) {
+	if len(data) == 0 {
+		return errMalformedBinary
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("unsupported binary revocation list version %d, expected %d", data[0], binaryVersion)
+	}
+	r := binaryReader{data: data[1:]}
+	list := RevocationList2020{Type: TypeRevocationList2020}
+	list.ID = r.string()
+	list.Purpose = r.string()
+	list.Issuer = r.string()
+	list.IssuanceDate = r.time()
+	list.ValidFrom = r.time()
+	list.ValidUntil = r.time()
+	list.revision = r.uvarint()
+	list.bits = int(r.uvarint())
+	// the compression byte must follow
+	if r.err == nil && len(r.data) == 0 {
+		r.err = errMalformedBinary
+	}
+	if r.err != nil {
+		return r.err
+	}
+	o, err := newOptions(WithCompression(Compression(r.data[0])))
+	if err != nil {
+		return
+	}
+	if err = o.validateID(list.ID); err != nil {
+		return
+	}
+	b := r.data[1:]
+	list.EncodedList = o.encoding.EncodeToString(b)
+	if err = list.decodeList(context.Background(), o); err != nil {
+		return
+	}
+	return rl.replace(list)
+}
+
+// MarshalJSON serializes the revocation list packing the current state of the bit set,
+// so that the encoded list is never stale. The receiver is copied before the list is locked,
+// use GetBytes or WriteTo when the list is updated concurrently
+func (rl RevocationList2020) MarshalJSON() ([]byte, error) {
+	defer rl.rLock()()
+	return rl.marshalJSON()
+}
+
+// marshalJSON serializes the revocation list, the caller must hold the lock
+func (rl *RevocationList2020) marshalJSON() (data []byte, err error) {
+	f, err := rl.serialized()
+	if err != nil {
+		return
+	}
+	if data, err = json.Marshal(f); err != nil || len(rl.Extras) == 0 {
+		return
+	}
+	// append the extra properties in a stable order, without overriding the standard ones
+	keys := make([]string, 0, len(rl.Extras))
+	for k := range rl.Extras {
+		if !serializedFields[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	data = data[:len(data)-1]
+	for _, k := range keys {
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(rl.Extras[k])
+		if err != nil {
+			return nil, err
+		}
+		data = append(append(append(append(data, ','), key...), ':'), value...)
+	}
+	data = append(data, '}')
+	return
+}
+
+// serializedList holds the serialized fields of a list, they are listed explicitly
+// to keep their order stable
+type serializedList struct {
+	ID           string `json:"id" yaml:"id"`
+	Type         string `json:"type" yaml:"type"`
+	Purpose      string `json:"statusPurpose,omitempty" yaml:"statusPurpose,omitempty"`
+	Issuer       string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	IssuanceDate string `json:"issuanceDate,omitempty" yaml:"issuanceDate,omitempty"`
+	ValidFrom    string `json:"validFrom,omitempty" yaml:"validFrom,omitempty"`
+	ValidUntil   string `json:"validUntil,omitempty" yaml:"validUntil,omitempty"`
+	EncodedList  string `json:"encodedList" yaml:"encodedList"`
+	Revision     uint64 `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// Capacity is set when the logical capacity is smaller than the bit set, see NewRevocationListWithBits
+	Capacity int `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	// SchemaVersion is set when the list uses extensions to the specification
+	SchemaVersion int `json:"schemaVersion,omitempty" yaml:"schemaVersion,omitempty"`
+}
+
+// serialized packs the bit set and returns the fields to serialize, the caller must hold the lock
+func (rl *RevocationList2020) serialized() (f serializedList, err error) {
+	var ebs string
+	if rl.opts.compactEmpty && rl.bitSet.count() == 0 {
+		ebs = compactEmptyPrefix + strconv.Itoa(rl.bitSet.size())
+	} else if ebs, err = pack(rl.bitSet, rl.opts); err != nil {
+		return
+	}
+	// the revocation purpose is implied, omit it for compatibility
+	purpose := rl.Purpose
+	if purpose == PurposeRevocation {
+		purpose = ""
+	}
+	var revision uint64
+	if rl.opts.withRevision {
+		revision = rl.revision
+	}
+	var capacity int
+	if rl.bits > 0 && rl.bits < len(rl.bitSet)*8 {
+		capacity = rl.bits
+	}
+	// tag the extended serializations
+	var schemaVersion int
+	if revision > 0 || capacity > 0 || strings.HasPrefix(ebs, compactEmptyPrefix) {
+		schemaVersion = SchemaVersion
+	}
+	f = serializedList{
+		ID:            rl.ID,
+		Type:          rl.Type,
+		Purpose:       purpose,
+		Issuer:        rl.Issuer,
+		IssuanceDate:  formatTime(rl.IssuanceDate),
+		ValidFrom:     formatTime(rl.ValidFrom),
+		ValidUntil:    formatTime(rl.ValidUntil),
+		EncodedList:   ebs,
+		Revision:      revision,
+		Capacity:      capacity,
+		SchemaVersion: schemaVersion,
+	}
+	return
+}
+
+// formatTime formats t in the RFC3339 UTC format, or returns an empty string if t is zero
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type bitSet []uint8
+
+// BitLocation returns the offset of the byte holding the bit of a credential index and
+// the mask of the bit within the byte, bits are stored least significant first.
+// It returns an error if the index is negative
+func BitLocation(index int) (byteOffset int, mask uint8, err error) {
+	if index < 0 {
+		err = fmt.Errorf("%w: %v", ErrIndexOutOfRange, index)
+		return
+	}
+	byteOffset, mask = bitLocation(index)
+	return
+}
+
+// bitLocation is BitLocation for an index that is known not to be negative
+func bitLocation(index int) (byteOffset int, mask uint8) {
+	return index / 8, uint8(1) << (index % 8)
+}
+
+// newBitSet allocates an empty bit set of kbSize KB, failing if the size is not positive
+// or if the capacity in bits would overflow an int
+func newBitSet(kbSize int) (bs bitSet, err error) {
+	if kbSize < 1 || kbSize > maxAllocSize {
+		err = fmt.Errorf("%w: cannot allocate %dkb", ErrSizeOutOfBounds, kbSize)
+		return
+	}
+	bs = make(bitSet, kbSize*1024)
+	return
+}
+
+// checkIndex returns an error if index is outside of the bit set
+func (bs bitSet) checkIndex(index int) error {
+	if index < 0 || index >= bs.len() {
+		return fmt.Errorf("%w 0-%d: %v", ErrIndexOutOfRange, bs.len(), index)
+	}
+	return nil
+}
+
+// getBit returns the value of the bit at index, or false if index is outside of the bit set
+func (bs bitSet) getBit(index int) bool {
+	if index < 0 || index >= bs.len() {
+		return false
+	}
+	pos, mask := bitLocation(index)
+	return bs[pos]&mask != 0
+}
+
+// getBitConstantTime returns the value of the bit at index without branching on it,
+// index must be within the bit set
+func (bs bitSet) getBitConstantTime(index int) bool {
+	bit := int(bs[index/8]>>(index%8)) & 1
+	return subtle.ConstantTimeSelect(bit, 1, 0) == 1
+}
+
+// trySetBit sets the bit at index, returning an error if index is outside of the bit set
+func (bs bitSet) trySetBit(index int, value bool) error {
+	if err := bs.checkIndex(index); err != nil {
+		return err
+	}
+	bs.setBit(index, value)
+	return nil
+}
+
+// setBit sets the bit at index, it does nothing if index is outside of the bit set
+func (bs bitSet) setBit(index int, value bool) {
+	if index < 0 || index >= bs.len() {
+		return
+	}
+	pos, mask := bitLocation(index)
+	bs.setMask(pos, mask, value)
+}
+
+// flipBit inverts the bit at index, index must be within the bit set
+func (bs bitSet) flipBit(index int) {
+	pos, mask := bitLocation(index)
+	bs[pos] ^= mask
+}
+
+// setMask sets (value true) or clears (value false) the bits of mask in the byte at pos
+func (bs bitSet) setMask(pos int, mask uint8, value bool) {
+	if value {
+		bs[pos] |= mask
+	} else {
+		bs[pos] &= ^mask
+	}
+}
+
+func (bs bitSet) len() int {
+	return 8 * len(bs)
+}
+
+// forEach calls fn for each bit set to 1 until fn returns false
+func (bs bitSet) forEach(fn func(index int) bool) {
+	for pos, b := range bs {
+		// skip the bytes with no bits set
+		if b == 0 {
+			continue
+		}
+		for j := 0; j < 8; j++ {
+			if b&(uint8(1)<<j) == 0 {
+				continue
+			}
+			if !fn(pos*8 + j) {
+				return
+			}
+		}
+	}
+}
+
+// firstZero returns the index of the first bit set to 0 starting from start,
+// or -1 if there is none
+func (bs bitSet) firstZero(start int) int {
+	for i := start; i < bs.len(); i++ {
+		// skip the bytes with all the bits set
+		if i%8 == 0 && bs[i/8] == 0xff {
+			i += 7
+			continue
+		}
+		if !bs.getBit(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// count returns the number of bits set to 1
+func (bs bitSet) count() (n int) {
+	for _, b := range bs {
+		n += bits.OnesCount8(b)
+	}
+	return
+}
+
+// size returns the size of the bitset int kb
+func (bs bitSet) size() int {
+	return len(bs) / 1024
+}
+
+// limitedReader reads from r failing once more than n bytes have been read
+type limitedReader struct {
+	r     io.Reader
+	n     int
+	limit int
+}
+
+func newLimitedReader(r io.Reader, limit int) *limitedReader {
+	return &limitedReader{r: r, n: limit, limit: limit}
+}
+
+func (lr *limitedReader) Read(p []byte) (n int, err error) {
+	if lr.n <= 0 {
+		// check if there is anything left to read past the limit
+		if n, err = lr.r.Read(make([]byte, 1)); n > 0 {
+			return 0, fmt.Errorf("payload exceeds the limit of %d bytes", lr.limit)
+		}
+		return
+	}
+	if len(p) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err = lr.r.Read(p)
+	lr.n -= n
+	return
+}
+
+func pack(set bitSet, o options) (s string, err error) {
+	return packContext(context.Background(), set, o)
+}
+
+// packContext compresses and encodes a bit set, checking ctx between chunks.
+// The output only depends on the bit set and the options: zlib has no timestamp or
+// OS fields, and the gzip header is left empty, with a zero mtime and an unknown OS.
+// Different Go versions may still compress the same bit set differently
+func packContext(ctx context.Context, set bitSet, o options) (s string, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if o.compression == Uncompressed {
+		s = o.encoding.EncodeToString(set)
+		return
+	}
+	// uniform bit sets are packed once, see uniformKey
+	if fill, ok := uniformFill(set); ok {
+		key := uniformKey{o.compression, o.compressionLevel, o.encoding, len(set), fill}
+		if v, ok := uniformPacked.Load(key); ok {
+			return v.(string), nil
+		}
+		defer func() {
+			if err == nil {
+				storeUniform(key, s)
+			}
+		}()
+	}
+	bb := bufferPool.Get().(*bytes.Buffer)
+	bb.Reset()
+	defer bufferPool.Put(bb)
+	// fist compress the data
+	if err = compressContext(ctx, bb, set, o); err != nil {
+		return
+	}
+	// encode to base64
+	s = o.encoding.EncodeToString(bb.Bytes())
+	return
+}
+
+// compressContext writes the compressed bit set to dst, checking ctx between chunks
+func compressContext(ctx context.Context, dst io.Writer, set bitSet, o options) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if o.compression == Uncompressed {
+		_, err = dst.Write(set)
+		return
+	}
+	w, err := getCompressor(o, dst)
+	if err != nil {
+		return
+	}
+	for len(set) > 0 {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		chunk := set
+		if len(chunk) > packChunkSize {
+			chunk = chunk[:packChunkSize]
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return
+		}
+		set = set[len(chunk):]
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	putCompressor(o, w)
+	return
+}
+
+// uniformKey identifies a bit set with all the bytes set to fill, either all zeros or
+// all ones, packed with the given options. Empty and fully revoked lists are common,
+// so their encoded lists are cached to skip compressing and decompressing them
+type uniformKey struct {
+	compression Compression
+	level       int
+	encoding    *base64.Encoding
+	size        int
+	fill        uint8
+}
+
+// maxUniformEntries bounds the number of cached uniform lists, the entries are never
+// evicted so once the cache is full the other uniform lists are compressed as usual
+const maxUniformEntries = 256
+
+var (
+	uniformPacked   sync.Map // uniformKey to encoded list
+	uniformUnpacked sync.Map // encoded list to uniformKey
+	uniformEntries  atomic.Int32
+)
+
+// storeUniform caches the encoded list s of a uniform bit set, unless the cache is full
+func storeUniform(key uniformKey, s string) {
+	if uniformEntries.Load() >= maxUniformEntries {
+		return
+	}
+	if _, loaded := uniformPacked.LoadOrStore(key, s); !loaded {
+		uniformEntries.Add(1)
+		uniformUnpacked.Store(s, key)
+	}
+}
+
+// loadUniform returns the key of an encoded list cached by storeUniform. The cache only
+// holds compressed lists, so it is skipped when the options ask for uncompressed data
+func loadUniform(s string, o *options) (key uniformKey, ok bool) {
+	if o.compression == Uncompressed {
+		return
+	}
+	v, ok := uniformUnpacked.Load(s)
+	if !ok {
+		return
+	}
+	key = v.(uniformKey)
+	ok = key.size <= o.unpackLimit()
+	return
+}
+
+// uniformFill returns the value of the bytes of set if they are either all 0x00 or all 0xff
+func uniformFill(set bitSet) (fill uint8, ok bool) {
+	if len(set) == 0 || (set[0] != 0x00 && set[0] != 0xff) {
+		return
+	}
+	for _, b := range set {
+		if b != set[0] {
+			return
+		}
+	}
+	return set[0], true
+}
+
+// bufferPool holds the buffers used to pack the lists
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// compressor is a compressing writer that can be reused
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressorKey identifies a pool of compressors with the same algorithm and level
+type compressorKey struct {
+	compression Compression
+	level       int
+}
+
+// compressorPools maps a compressorKey to a *sync.Pool of compressors
+var compressorPools sync.Map
+
+// getCompressor returns a compressor writing to w, reusing a pooled one when available
+func getCompressor(o options, w io.Writer) (compressor, error) {
+	p, _ := compressorPools.LoadOrStore(compressorKey{o.compression, o.compressionLevel}, new(sync.Pool))
+	if c, ok := p.(*sync.Pool).Get().(compressor); ok {
+		c.Reset(w)
+		return c, nil
+	}
+	if o.compression == Gzip {
+		return gzip.NewWriterLevel(w, o.compressionLevel)
+	}
+	return zlib.NewWriterLevel(w, o.compressionLevel)
+}
+
+// putCompressor returns a compressor to its pool
+func putCompressor(o options, c compressor) {
+	if p, ok := compressorPools.Load(compressorKey{o.compression, o.compressionLevel}); ok {
+		p.(*sync.Pool).Put(c)
+	}
+}
+
+// unpack decodes and decompress an encoded list, o is updated with
+// the encoding and compression detected while decoding
+func unpack(s string, o *options) (bs bitSet, err error) {
+	return unpackContext(context.Background(), s, o)
+}
+
+// unpackContext is like unpack but checks ctx while decompressing
+func unpackContext(ctx context.Context, s string, o *options) (bs bitSet, err error) {
+	// pretty printed or hand edited lists may be wrapped with whitespace
+	if strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	// expand the uniform bit sets without decompressing them
+	if key, ok := loadUniform(s, o); ok {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		o.encoding, o.compression = key.encoding, key.compression
+		bs = make(bitSet, key.size)
+		if key.fill != 0 {
+			for i := range bs {
+				bs[i] = key.fill
+			}
+		}
+		o.debug(ctx, "revocation list decoded", "compression", o.compression, "size", len(bs), "uniform", true)
+		return
+	}
+	b, err := decode(s, o)
+	if err != nil {
+		o.debug(ctx, "decoding revocation list failed", "encodedLength", len(s), "error", err)
+		err = fmt.Errorf("%w: %w", ErrCorruptEncodedList, err)
+		return
+	}
+	if bs, err = decompressContext(ctx, b, o); err != nil {
+		o.debug(ctx, "decompressing revocation list failed", "decodedLength", len(b), "compression", o.compression, "error", err)
+		return
+	}
+	o.debug(ctx, "revocation list decoded", "decodedLength", len(b), "compression", o.compression, "size", len(bs))
+	return
+}
+
+// decompressContext decompresses a bit set, o is updated with the compression detected.
+// Data is read as uncompressed only when the options ask for it, since a plain
+// bit set may happen to start with a valid gzip or zlib header
+func decompressContext(ctx context.Context, b []byte, o *options) (bs bitSet, err error) {
+	// pick the decompressor looking at the header
+	var zr io.ReadCloser
+	if o.compression != Uncompressed {
+		if o.compression, err = detectCompression(b); err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorruptEncodedList, err)
+			return
+		}
+	}
+	switch o.compression {
+	case Gzip:
+		zr, err = gzip.NewReader(bytes.NewReader(b))
+	case Zlib:
+		zr, err = zlib.NewReader(bytes.NewReader(b))
+	case Uncompressed:
+		zr = io.NopCloser(bytes.NewReader(b))
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorruptEncodedList, err)
+		return
+	}
+	// read the whole stream before closing the reader, reading at most one byte
+	// past the limit to reject oversized payloads before allocating them
+	limit := o.unpackLimit()
+	var r io.Reader = &contextReader{ctx, zr}
+	if !o.trustedInput {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	if bs, err = io.ReadAll(r); err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrCorruptEncodedList, err)
+		}
+		return
+	}
+	if len(bs) > limit {
+		if limit < o.maxSize*1024 {
+			err = fmt.Errorf("decompressed list exceeds the limit of %d bytes", limit)
+		} else {
+			err = fmt.Errorf("%w: must be between %d and %d, got more than %d", ErrSizeOutOfBounds, minBitSetSize, o.maxSize, o.maxSize)
+		}
+		return
+	}
+	if err = zr.Close(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorruptEncodedList, err)
+	}
+	return
+}
+
+// withListID adds the ID of the list to the errors caused by a corrupt encoded list
+func withListID(id string, err error) error {
+	if errors.Is(err, ErrCorruptEncodedList) {
+		return fmt.Errorf("revocation list %v: %w", id, err)
+	}
+	return err
+}
+
+// contextReader fails reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// detectCompression looks at the header of the data to find out how it was compressed,
+// data that does not start with a gzip or zlib header is rejected
+func detectCompression(b []byte) (c Compression, err error) {
+	switch {
+	case len(b) < 2:
+	// gzip magic bytes
+	case b[0] == 0x1f && b[1] == 0x8b:
+		c = Gzip
+		return
+	// zlib header: deflate method, window size up to 32K and a valid checksum
+	case b[0]&0x0f == 8 && b[0]>>4 <= 7 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0:
+		c = Zlib
+		return
+	}
+	err = errors.New("unknown compression, use WithCompression(Uncompressed) to read uncompressed lists")
+	return
+}
+
+// decode tries the configured encoding first and then falls back to
+// the other base64 alphabet
+func decode(s string, o *options) (b []byte, err error) {
+	if b, err = o.encoding.DecodeString(s); err == nil {
+		return
+	}
+	// StatusList2021 issuers may omit the padding
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, e := enc.DecodeString(s); e == nil {
+			o.encoding = enc
+			return b, nil
+		}
+	}
+	return
 }
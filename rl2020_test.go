@@ -1,13 +1,32 @@
 package rl2020
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewRevocationList(t *testing.T) {
+	defaultOptions, _ := newOptions()
+
 	type args struct {
 		id     string
 		kbSize int
@@ -26,10 +45,13 @@ func TestNewRevocationList(t *testing.T) {
 			},
 			func() *RevocationList2020 {
 				return &RevocationList2020{
-					"test-1",
-					TypeRevocationList2020,
-					"eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQewAAAP//QAAAAQ==",
-					make([]byte, 16384),
+					ID:          "test-1",
+					Type:        TypeRevocationList2020,
+					Purpose:     PurposeRevocation,
+					EncodedList: "eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB",
+					bitSet:      make([]byte, 16384),
+					opts:        defaultOptions,
+					mu:          new(sync.RWMutex),
 				}
 			},
 			nil,
@@ -43,7 +65,7 @@ func TestNewRevocationList(t *testing.T) {
 			func() *RevocationList2020 {
 				return nil
 			},
-			fmt.Errorf("size must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 1),
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 1),
 		},
 		{
 			"FAIL: size too big",
@@ -54,7 +76,7 @@ func TestNewRevocationList(t *testing.T) {
 			func() *RevocationList2020 {
 				return nil
 			},
-			fmt.Errorf("size must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 129),
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 129),
 		},
 	}
 	for _, tt := range tests {
@@ -71,15 +93,53 @@ func TestNewRevocationList(t *testing.T) {
 	}
 }
 
+func TestNewCredentialStatus(t *testing.T) {
+	type args struct {
+		rlCredential string
+		rlIndex      int
+	}
+	tests := []struct {
+		name string
+		args args
+		want CredentialStatusJSON
+	}{
+		{
+			"PASS: can generate",
+			args{
+				rlCredential: "https://example.com/credentials/status/3",
+				rlIndex:      94567,
+			},
+			CredentialStatusJSON{
+				ID:                       "https://example.com/credentials/status/3/94567",
+				Type:                     TypeRevocationList2020Status,
+				RevocationListIndex:      94567,
+				RevocationListCredential: "https://example.com/credentials/status/3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCredentialStatus(tt.args.rlCredential, tt.args.rlIndex)
+			assert.Equal(t, tt.want, got)
+			// the returned status can be used directly to query a list
+			rl, err := NewRevocationList(tt.args.rlCredential, 16)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(tt.args.rlIndex))
+			isIt, err := rl.IsRevoked(got)
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+			// so can the concrete type
+			isIt, err = rl.IsRevoked(tt.want)
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+		})
+	}
+}
+
 func TestRevocationList2020_Update(t *testing.T) {
 
 	cs := func(idx int, cred string) CredentialStatus {
-		return CredentialStatusJSON{
-			ID:                       fmt.Sprint(cred, "/", idx),
-			Type:                     TypeRevocationList2020Status,
-			RevocationListIndex:      idx,
-			RevocationListCredential: cred,
-		}
+		return NewCredentialStatus(cred, idx)
 	}
 
 	type args struct {
@@ -183,8 +243,3051 @@ func TestRevocationList2020_Serialization(t *testing.T) {
 				assert.Equal(t, tt.wantErr.Error(), err.Error())
 			}
 			assert.NoError(t, err)
+			// the revision is not serialized by default
+			assert.Zero(t, rlN.Revision())
+			rlN.revision = rl.revision
 			// compare
 			assert.Equal(t, rl, rlN)
 		})
 	}
 }
+
+func TestRevocationList2020_RoundTrip(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		kbSize   int
+		toRevoke []int // element to revoke
+	}{
+		{
+			"PASS: bits near the end of a 64kb list survive",
+			64,
+			[]int{0, 500000, 524287},
+		},
+		{
+			"PASS: bits near the end of a 128kb list survive",
+			128,
+			[]int{1, 1048575},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", tt.kbSize)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.Revoke(tt.toRevoke...))
+			// serialize the revocation list
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			// load the serialized stuff
+			rlN, err := NewRevocationListFromJSON(rlB)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kbSize, rlN.Size())
+			for _, i := range tt.toRevoke {
+				isIt, err := rlN.IsRevoked(NewCredentialStatus("c0", i))
+				assert.NoError(t, err)
+				assert.True(t, isIt)
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_IsRevoked(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		status  CredentialStatus
+		want    bool
+		wantErr error
+	}{
+		{
+			"PASS: not revoked",
+			NewCredentialStatus("c0", 11),
+			false,
+			nil,
+		},
+		{
+			"PASS: revoked",
+			NewCredentialStatus("c0", 10),
+			true,
+			nil,
+		},
+		{
+			"FAIL: wrong status type",
+			CredentialStatusJSON{
+				ID:                       "c0/10",
+				Type:                     "BogusStatus",
+				RevocationListIndex:      10,
+				RevocationListCredential: "c0",
+			},
+			false,
+			fmt.Errorf("unsupported type BogusStatus, expected %v", TypeRevocationList2020Status),
+		},
+		{
+			"FAIL: empty status id",
+			CredentialStatusJSON{
+				Type:                     TypeRevocationList2020Status,
+				RevocationListIndex:      10,
+				RevocationListCredential: "c0",
+			},
+			false,
+			fmt.Errorf("credential status ID is empty"),
+		},
+		{
+			"FAIL: wrong list",
+			NewCredentialStatus("c1", 10),
+			false,
+			fmt.Errorf("wrong revocation list, expected c0, got c1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, rl.Revoke(10))
+			got, err := rl.IsRevoked(tt.status)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_RevokedCount(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		kbSize int
+		revoke []int
+		reset  []int
+		want   int
+	}{
+		{
+			"PASS: empty list",
+			16,
+			nil,
+			nil,
+			0,
+		},
+		{
+			"PASS: revocations only",
+			16,
+			[]int{0, 7, 8, 131071},
+			nil,
+			4,
+		},
+		{
+			"PASS: revoke the same index twice",
+			16,
+			[]int{10, 10, 11},
+			nil,
+			2,
+		},
+		{
+			"PASS: revocations and resets",
+			32,
+			[]int{1, 2, 3, 200000},
+			[]int{2, 3, 4},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", tt.kbSize)
+			assert.NoError(t, rl.Revoke(tt.revoke...))
+			assert.NoError(t, rl.Reset(tt.reset...))
+			assert.Equal(t, tt.want, rl.RevokedCount())
+			// the count survives serialization
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(rlB)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rlN.RevokedCount())
+		})
+	}
+}
+
+func TestRevocationList2020_RevokedIndexes(t *testing.T) {
+
+	all := make([]int, 16*1024*8)
+	for i := range all {
+		all[i] = i
+	}
+
+	tests := []struct {
+		name   string
+		revoke []int
+		want   []int
+	}{
+		{
+			"PASS: empty list",
+			nil,
+			[]int{},
+		},
+		{
+			"PASS: sparse revocations near both ends",
+			[]int{131071, 0, 3, 131064, 9},
+			[]int{0, 3, 9, 131064, 131071},
+		},
+		{
+			"PASS: fully revoked list",
+			all,
+			all,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, rl.Revoke(tt.revoke...))
+			assert.Equal(t, tt.want, rl.RevokedIndexes())
+		})
+	}
+}
+
+func TestRevocationList2020_ForEachRevoked(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 10, 100, 1000))
+	// stop after the second index
+	var got []int
+	rl.ForEachRevoked(func(index int) bool {
+		got = append(got, index)
+		return len(got) < 2
+	})
+	assert.Equal(t, []int{1, 10}, got)
+}
+
+func TestRevocationList2020_Concurrency(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+
+	var wg sync.WaitGroup
+	// writers
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, rl.Revoke(w*1000+i))
+				assert.NoError(t, rl.Reset(w*1000+i/2))
+			}
+		}(w)
+	}
+	// readers
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				_, err := rl.IsRevoked(NewCredentialStatus("c0", r*100+i))
+				assert.NoError(t, err)
+				rl.RevokedCount()
+				rl.RevokedIndexes()
+			}
+		}(r)
+	}
+	wg.Wait()
+	// the last half of each writer's range is still revoked
+	assert.Equal(t, 4*25, rl.RevokedCount())
+}
+
+func TestRevocationList2020_FindFirstAvailable(t *testing.T) {
+
+	all := make([]int, 16*1024*8)
+	for i := range all {
+		all[i] = i
+	}
+
+	tests := []struct {
+		name    string
+		revoke  []int
+		start   int
+		want    int
+		wantErr error
+	}{
+		{
+			"PASS: fresh list",
+			nil,
+			0,
+			0,
+			nil,
+		},
+		{
+			"PASS: gap in the middle",
+			append(all[:100:100], all[101:200]...),
+			0,
+			100,
+			nil,
+		},
+		{
+			"PASS: from a start index",
+			[]int{10, 11, 12},
+			10,
+			13,
+			nil,
+		},
+		{
+			"PASS: last index available",
+			all[:len(all)-1],
+			0,
+			131071,
+			nil,
+		},
+		{
+			"FAIL: full list",
+			all,
+			0,
+			0,
+			fmt.Errorf("no available credential index from 0"),
+		},
+		{
+			"FAIL: no index available after start",
+			all[1000:],
+			1000,
+			0,
+			fmt.Errorf("no available credential index from 1000"),
+		},
+		{
+			"FAIL: start out of range",
+			nil,
+			131072,
+			0,
+			fmt.Errorf("credential index out of range 0-131072: 131072"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, rl.Revoke(tt.revoke...))
+			got, err := rl.FindFirstAvailableFrom(tt.start)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+			if tt.start == 0 {
+				first, firstErr := rl.FindFirstAvailable()
+				assert.Equal(t, got, first)
+				assert.Equal(t, err, firstErr)
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_Resize(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		kbSize  int
+		revoke  []int
+		resize  int
+		wantErr error
+	}{
+		{
+			"PASS: grow from 16kb to 32kb",
+			16,
+			[]int{0, 131000, 131071},
+			32,
+			nil,
+		},
+		{
+			"PASS: shrink from 32kb to 16kb",
+			32,
+			[]int{0, 131071},
+			16,
+			nil,
+		},
+		{
+			"PASS: same size",
+			16,
+			[]int{12},
+			16,
+			nil,
+		},
+		{
+			"FAIL: shrink would drop a revocation",
+			32,
+			[]int{0, 131072},
+			16,
+			fmt.Errorf("cannot resize to 16kb, the list has revoked credentials beyond index 131071"),
+		},
+		{
+			"FAIL: size too big",
+			16,
+			nil,
+			129,
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 129),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", tt.kbSize)
+			assert.NoError(t, rl.Revoke(tt.revoke...))
+			err := rl.Resize(tt.resize)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				// the list is left untouched
+				assert.Equal(t, tt.kbSize, rl.Size())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.resize, rl.Size())
+			assert.Equal(t, tt.revoke, rl.RevokedIndexes())
+			// the encoded list reflects the new size
+			rlB, err := rl.GetBytes()
+			assert.NoError(t, err)
+			rlN, err := NewRevocationListFromJSON(rlB)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.resize, rlN.Size())
+			assert.Equal(t, tt.revoke, rlN.RevokedIndexes())
+		})
+	}
+}
+
+func TestRevocationList2020_Clone(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 2, 3))
+
+	clone := rl.Clone()
+	assert.Equal(t, rl.ID, clone.ID)
+	assert.Equal(t, rl.Type, clone.Type)
+	assert.Equal(t, rl.EncodedList, clone.EncodedList)
+	assert.Equal(t, rl.BitSet(), clone.BitSet())
+
+	// revoking on the clone does not affect the original
+	assert.NoError(t, clone.Revoke(4, 5))
+	assert.Equal(t, 3, rl.RevokedCount())
+	assert.Equal(t, 5, clone.RevokedCount())
+	// and vice versa
+	assert.NoError(t, rl.Reset(1, 2, 3))
+	assert.Equal(t, 0, rl.RevokedCount())
+	assert.Equal(t, 5, clone.RevokedCount())
+	assert.NotEqual(t, rl.EncodedList, clone.EncodedList)
+}
+
+func TestRevocationList2020_Equal(t *testing.T) {
+
+	// encode a bit set with a specific zlib compression level
+	encode := func(bs bitSet, level int) string {
+		var bb bytes.Buffer
+		w, _ := zlib.NewWriterLevel(&bb, level)
+		_, _ = w.Write(bs)
+		_ = w.Close()
+		return base64.StdEncoding.EncodeToString(bb.Bytes())
+	}
+	// build a list from an encoded list
+	load := func(id string, encodedList string) RevocationList2020 {
+		rl, err := NewRevocationListFromJSON([]byte(fmt.Sprintf(`{"id":"%s","type":"RevocationList2020","encodedList":"%s"}`, id, encodedList)))
+		assert.NoError(t, err)
+		return rl
+	}
+
+	bs, _ := newBitSet(16)
+	for _, i := range []int{1, 300, 9000, 131071} {
+		bs.setBit(i, true)
+	}
+	other, _ := newBitSet(16)
+	other.setBit(1, true)
+
+	tests := []struct {
+		name string
+		a    RevocationList2020
+		b    RevocationList2020
+		want bool
+	}{
+		{
+			"PASS: different compression levels",
+			load("c0", encode(bs, zlib.BestSpeed)),
+			load("c0", encode(bs, zlib.BestCompression)),
+			true,
+		},
+		{
+			"PASS: no compression and default compression",
+			load("c0", encode(bs, zlib.NoCompression)),
+			load("c0", encode(bs, zlib.DefaultCompression)),
+			true,
+		},
+		{
+			"FAIL: different revocations",
+			load("c0", encode(bs, zlib.DefaultCompression)),
+			load("c0", encode(other, zlib.DefaultCompression)),
+			false,
+		},
+		{
+			"FAIL: different IDs",
+			load("c0", encode(bs, zlib.DefaultCompression)),
+			load("c1", encode(bs, zlib.DefaultCompression)),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.want {
+				assert.NotEqual(t, tt.a.EncodedList, tt.b.EncodedList)
+			}
+			assert.Equal(t, tt.want, tt.a.Equal(&tt.b))
+			assert.Equal(t, tt.want, tt.b.Equal(&tt.a))
+			assert.True(t, tt.a.Equal(&tt.a))
+		})
+	}
+}
+
+func TestRevocationList2020_MarshalJSON(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1))
+	// set bits bypassing Update, the encoded list is now stale
+	rl.bitSet.setBit(2, true)
+	rl.bitSet.setBit(131071, true)
+
+	for _, marshal := range []func() ([]byte, error){
+		func() ([]byte, error) { return json.Marshal(rl) },
+		func() ([]byte, error) { return json.Marshal(&rl) },
+		rl.GetBytes,
+	} {
+		data, err := marshal()
+		assert.NoError(t, err)
+		rlN, err := NewRevocationListFromJSON(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 131071}, rlN.RevokedIndexes())
+	}
+	// a list passed by value omits the zero dates and the implied purpose
+	byValue, err := json.Marshal(rl)
+	assert.NoError(t, err)
+	byPointer, err := rl.GetBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, byPointer, byValue)
+	assert.NotContains(t, string(byValue), "statusPurpose")
+	assert.NotContains(t, string(byValue), "0001-01-01")
+}
+
+func TestRevocationList2020_UnmarshalJSON(t *testing.T) {
+
+	// a list that decompresses to a partial KB
+	o, _ := newOptions()
+	partial, _ := pack(make(bitSet, 16*1024+1), o)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []int
+		wantErr error
+	}{
+		{
+			"PASS: valid list",
+			`{"id":"c0","type":"RevocationList2020","encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			[]int{7812},
+			nil,
+		},
+		{
+			"FAIL: empty id",
+			`{"id":" ","type":"RevocationList2020","encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			nil,
+			fmt.Errorf("revocation list ID is empty"),
+		},
+		{
+			"FAIL: wrong type",
+			`{"id":"c0","type":"StatusList2021","encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			nil,
+			fmt.Errorf("unsupported type StatusList2021, expected RevocationList2020"),
+		},
+		{
+			"PASS: whitespace in the encoded list",
+			`{"id":"c0","type":"RevocationList2020","encodedList":"\n  eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEf\n  AAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ==\t \r\n"}`,
+			[]int{7812},
+			nil,
+		},
+		{
+			"PASS: spaces in the encoded list",
+			`{"id":"c0","type":"RevocationList2020","encodedList":"eJzsxjERAAAIBCAj2D+tkyH+ DyYGqLEfAAAAAAAAAAAA AAAAAAAgzg0AAzwAEQ=="}`,
+			[]int{7812},
+			nil,
+		},
+		{
+			"PASS: type array",
+			`{"id":"c0","type":["RevocationList2020"],"encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			[]int{7812},
+			nil,
+		},
+		{
+			"PASS: type array with other types",
+			`{"id":"c0","type":["StatusList","RevocationList2020"],"encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			[]int{7812},
+			nil,
+		},
+		{
+			"FAIL: wrong type array",
+			`{"id":"c0","type":["StatusList2021"],"encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			nil,
+			fmt.Errorf("unsupported type [StatusList2021], expected RevocationList2020"),
+		},
+		{
+			"FAIL: invalid type",
+			`{"id":"c0","type":1,"encodedList":"eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="}`,
+			nil,
+			fmt.Errorf("unsupported type 1, expected RevocationList2020"),
+		},
+		{
+			"FAIL: size out of range",
+			`{"id":"c0","type":"RevocationList2020","encodedList":"eJxjYBgFo2AUjFQAAAQAAAE="}`,
+			nil,
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 1),
+		},
+		{
+			"FAIL: partial KB",
+			fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s"}`, partial),
+			nil,
+			fmt.Errorf("corrupt encoded list: decompressed to 16385 bytes, not a whole number of KB"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// standalone
+			var rl RevocationList2020
+			err := json.Unmarshal([]byte(tt.data), &rl)
+			// nested in another struct
+			var nested struct {
+				List RevocationList2020 `json:"list"`
+			}
+			nestedErr := json.Unmarshal([]byte(fmt.Sprintf(`{"list":%s}`, tt.data)), &nested)
+			// with the constructor
+			_, ctorErr := NewRevocationListFromJSON([]byte(tt.data))
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.NoError(t, nestedErr)
+				assert.NoError(t, ctorErr)
+				assert.Equal(t, tt.want, rl.RevokedIndexes())
+				assert.Equal(t, tt.want, nested.List.RevokedIndexes())
+				isIt, err := nested.List.IsRevoked(NewCredentialStatus("c0", tt.want[0]))
+				assert.NoError(t, err)
+				assert.True(t, isIt)
+			} else {
+				for _, err := range []error{err, nestedErr, ctorErr} {
+					assert.Error(t, err)
+					assert.Equal(t, tt.wantErr.Error(), err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_AreRevoked(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		statuses []CredentialStatus
+		want     []bool
+		wantErr  error
+	}{
+		{
+			"PASS: empty batch",
+			nil,
+			[]bool{},
+			nil,
+		},
+		{
+			"PASS: mixed batch",
+			[]CredentialStatus{
+				NewCredentialStatus("c0", 1),
+				NewCredentialStatus("c0", 2),
+				NewCredentialStatus("c0", 100),
+				NewCredentialStatus("c0", 131071),
+			},
+			[]bool{true, false, true, false},
+			nil,
+		},
+		{
+			"FAIL: status for another list",
+			[]CredentialStatus{
+				NewCredentialStatus("c0", 1),
+				NewCredentialStatus("c1", 2),
+			},
+			nil,
+			fmt.Errorf("credential status 1: wrong revocation list, expected c0, got c1"),
+		},
+		{
+			"FAIL: index out of range",
+			[]CredentialStatus{
+				NewCredentialStatus("c0", 1),
+				NewCredentialStatus("c0", 2),
+				NewCredentialStatus("c0", 131072),
+			},
+			nil,
+			fmt.Errorf("credential status 2: credential index out of range 0-131072: 131072"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, rl.Revoke(1, 100))
+			got, err := rl.AreRevoked(tt.statuses)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.Nil(t, got)
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_UpdateRange(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		revoke   [2]int
+		reset    [2]int
+		expected map[int]bool
+		wantErr  error
+	}{
+		{
+			"PASS: revoke a range",
+			[2]int{100, 200},
+			[2]int{0, 0},
+			map[int]bool{99: false, 100: true, 150: true, 199: true, 200: false},
+			nil,
+		},
+		{
+			"PASS: revoke and reset overlapping ranges",
+			[2]int{100, 200},
+			[2]int{150, 250},
+			map[int]bool{99: false, 100: true, 149: true, 150: false, 199: false, 200: false},
+			nil,
+		},
+		{
+			"PASS: revoke the whole list",
+			[2]int{0, 131072},
+			[2]int{1, 131071},
+			map[int]bool{0: true, 1: false, 131070: false, 131071: true},
+			nil,
+		},
+		{
+			"FAIL: range beyond capacity",
+			[2]int{131000, 131073},
+			[2]int{0, 0},
+			map[int]bool{131000: false},
+			fmt.Errorf("credential index out of range 0-131072: [131000, 131073)"),
+		},
+		{
+			"FAIL: inverted range",
+			[2]int{200, 100},
+			[2]int{0, 0},
+			map[int]bool{150: false},
+			fmt.Errorf("credential index out of range 0-131072: [200, 100)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			if err := rl.RevokeRange(tt.revoke[0], tt.revoke[1]); tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+			assert.NoError(t, rl.ResetRange(tt.reset[0], tt.reset[1]))
+			for i, status := range tt.expected {
+				isIt, err := rl.IsRevoked(NewCredentialStatus("c0", i))
+				assert.NoError(t, err)
+				assert.Equal(t, status, isIt, "index %d", i)
+			}
+			// the encoded list is updated
+			rlB, _ := rl.GetBytes()
+			rlN, _ := NewRevocationListFromJSON(rlB)
+			assert.Equal(t, rl.EncodedList, rlN.EncodedList)
+		})
+	}
+}
+
+func TestRevocationList2020_RevokeAll(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 2, 3))
+
+	assert.NoError(t, rl.RevokeAll())
+	assert.Equal(t, rl.Capacity(), rl.RevokedCount())
+	_, err := rl.FindFirstAvailable()
+	assert.Error(t, err)
+
+	assert.NoError(t, rl.ResetAll())
+	assert.Equal(t, 0, rl.RevokedCount())
+	empty, _ := NewRevocationList("c0", 16)
+	assert.Equal(t, empty.EncodedList, rl.EncodedList)
+}
+
+func TestRevocationList2020_Stats(t *testing.T) {
+
+	tests := []struct {
+		name string
+		rlFn func() RevocationList2020
+		want Stats
+	}{
+		{
+			"PASS: empty list",
+			func() RevocationList2020 {
+				rl, _ := NewRevocationList("c0", 16)
+				return rl
+			},
+			Stats{Capacity: 131072, Revoked: 0, Available: 131072, FillRatio: 0},
+		},
+		{
+			"PASS: quarter full list",
+			func() RevocationList2020 {
+				rl, _ := NewRevocationList("c0", 16)
+				_ = rl.RevokeRange(0, 32768)
+				return rl
+			},
+			Stats{Capacity: 131072, Revoked: 32768, Available: 98304, FillRatio: 0.25},
+		},
+		{
+			"PASS: degenerate list",
+			func() RevocationList2020 {
+				return RevocationList2020{}
+			},
+			Stats{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := tt.rlFn()
+			assert.Equal(t, tt.want, rl.Stats())
+			assert.Equal(t, tt.want.Available, rl.CapacityRemaining())
+		})
+	}
+}
+
+func TestNewRevocationListFromBools(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		size    int
+		revoke  []int
+		wantErr error
+	}{
+		{
+			"PASS: round trip on a 16kb list",
+			16 * 1024 * 8,
+			[]int{0, 9, 1000, 131071},
+			nil,
+		},
+		{
+			"FAIL: partial kb",
+			16*1024*8 + 1,
+			nil,
+			fmt.Errorf("number of credentials must be a multiple of 8192, got 131073"),
+		},
+		{
+			"FAIL: size too small",
+			8 * 1024 * 8,
+			nil,
+			fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 8),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bits := make([]bool, tt.size)
+			for _, i := range tt.revoke {
+				bits[i] = true
+			}
+			rl, err := NewRevocationListFromBools("c0", bits)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.revoke, rl.RevokedIndexes())
+			assert.Equal(t, bits, rl.ToBoolSlice())
+			// the encoded list matches the bits
+			expected, _ := NewRevocationList("c0", tt.size/(8*1024))
+			assert.NoError(t, expected.Revoke(tt.revoke...))
+			assert.Equal(t, expected.EncodedList, rl.EncodedList)
+		})
+	}
+}
+
+func TestRevocationList2020_String(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(1, 2, 3, 4))
+
+	want := "RevocationList2020(id=https://example.com/credentials/status/3, capacity=131072, revoked=4, size=16KB)"
+	assert.Equal(t, want, rl.String())
+	assert.Equal(t, want, fmt.Sprint(rl))
+	assert.Equal(t, want, fmt.Sprint(&rl))
+	assert.NotContains(t, rl.String(), rl.EncodedList)
+}
+
+func TestNewRevocationListFromReader(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	data, _ := rl.GetBytes()
+
+	tests := []struct {
+		name    string
+		reader  func() io.Reader
+		opts    []Option
+		wantErr error
+	}{
+		{
+			"PASS: bytes reader",
+			func() io.Reader {
+				return bytes.NewReader(data)
+			},
+			nil,
+			nil,
+		},
+		{
+			"PASS: pipe",
+			func() io.Reader {
+				pr, pw := io.Pipe()
+				go func() {
+					// write one byte at a time
+					_, err := io.Copy(pw, iotest.OneByteReader(bytes.NewReader(data)))
+					_ = pw.CloseWithError(err)
+				}()
+				return pr
+			},
+			nil,
+			nil,
+		},
+		{
+			"FAIL: oversized payload",
+			func() io.Reader {
+				return strings.NewReader(fmt.Sprintf(`{"id":"%s"}`, strings.Repeat("x", 4096)))
+			},
+			[]Option{WithDecompressionLimit(1024)},
+			fmt.Errorf("payload exceeds the limit of 2048 bytes"),
+		},
+		{
+			"FAIL: invalid list",
+			func() io.Reader {
+				return strings.NewReader(`{"id":"c0","type":"StatusList2021"}`)
+			},
+			nil,
+			fmt.Errorf("unsupported type StatusList2021, expected RevocationList2020"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRevocationListFromReader(tt.reader(), tt.opts...)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, rl.Equal(&got))
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_WriteTo(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	want, err := rl.GetBytes()
+	assert.NoError(t, err)
+
+	var bb bytes.Buffer
+	var w io.WriterTo = &rl
+	n, err := w.WriteTo(&bb)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(want)), n)
+	assert.Equal(t, want, bb.Bytes())
+}
+
+// cancelAfter is a context that gets cancelled after n checks
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestRevocationList2020_UpdateContext(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 128)
+	assert.NoError(t, rl.Revoke(1))
+	encodedList := rl.EncodedList
+
+	// cancel while packing
+	err := rl.UpdateContext(&cancelAfter{context.Background(), 3}, Revoke, 2, 3, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	// the list is left unchanged
+	assert.Equal(t, []int{1}, rl.RevokedIndexes())
+	assert.Equal(t, encodedList, rl.EncodedList)
+
+	// an already cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, rl.UpdateContext(ctx, Reset, 1), context.Canceled)
+	assert.Equal(t, []int{1}, rl.RevokedIndexes())
+
+	// a live context
+	assert.NoError(t, rl.UpdateContext(context.Background(), Revoke, 2, 3))
+	assert.Equal(t, []int{1, 2, 3}, rl.RevokedIndexes())
+}
+
+func TestNewRevocationListFromJSONContext(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 128)
+	assert.NoError(t, rl.Revoke(1))
+	data, _ := rl.GetBytes()
+
+	// cancel while unpacking
+	_, err := NewRevocationListFromJSONContext(&cancelAfter{context.Background(), 3}, data)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	rlN, err := NewRevocationListFromJSONContext(context.Background(), data)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&rlN))
+}
+
+func TestNewRevocationListFromJSON_Oversized(t *testing.T) {
+	// a list that decompresses to 64MB
+	o, _ := newOptions()
+	bomb, err := pack(make(bitSet, 64*1024*1024), o)
+	assert.NoError(t, err)
+	data := []byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s"}`, bomb))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = NewRevocationListFromJSON(data)
+	runtime.ReadMemStats(&after)
+
+	assert.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("size out of bounds: must be between %d and %d, got more than %d", minBitSetSize, maxBitSetSize, maxBitSetSize), err.Error())
+	// the payload is rejected without decompressing it all
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(8*1024*1024))
+}
+
+func TestValidateCredentialStatus(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		status  CredentialStatus
+		wantErr error
+	}{
+		{
+			"PASS: well formed status",
+			NewCredentialStatus("https://example.com/credentials/status/3", 94567),
+			nil,
+		},
+		{
+			"FAIL: inconsistent index",
+			CredentialStatusJSON{
+				ID:                       "https://example.com/credentials/status/3/94567",
+				Type:                     TypeRevocationList2020Status,
+				RevocationListIndex:      94568,
+				RevocationListCredential: "https://example.com/credentials/status/3",
+			},
+			fmt.Errorf("inconsistent credential status ID, expected https://example.com/credentials/status/3/94568, got https://example.com/credentials/status/3/94567"),
+		},
+		{
+			"FAIL: inconsistent list",
+			CredentialStatusJSON{
+				ID:                       "https://example.com/credentials/status/3/94567",
+				Type:                     TypeRevocationList2020Status,
+				RevocationListIndex:      94567,
+				RevocationListCredential: "https://example.com/credentials/status/4",
+			},
+			fmt.Errorf("inconsistent credential status ID, expected https://example.com/credentials/status/4/94567, got https://example.com/credentials/status/3/94567"),
+		},
+		{
+			"FAIL: wrong type",
+			CredentialStatusJSON{
+				ID:                       "https://example.com/credentials/status/3/94567",
+				Type:                     "StatusList2021Entry",
+				RevocationListIndex:      94567,
+				RevocationListCredential: "https://example.com/credentials/status/3",
+			},
+			fmt.Errorf("unsupported type StatusList2021Entry, expected %v", TypeRevocationList2020Status),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCredentialStatus(tt.status)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestCredentialStatus_TypeSpelling(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(94567))
+
+	tests := []struct {
+		name    string
+		typ     string
+		wantErr error
+	}{
+		{
+			"PASS: spec spelling",
+			"RevocationList2020Status",
+			nil,
+		},
+		{
+			"PASS: legacy spelling",
+			"RevocationList2020status",
+			nil,
+		},
+		{
+			"FAIL: other spelling",
+			"revocationList2020Status",
+			fmt.Errorf("unsupported type revocationList2020Status, expected RevocationList2020Status"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cs CredentialStatusJSON
+			err := json.Unmarshal([]byte(fmt.Sprintf(`{
+				"id": "https://example.com/credentials/status/3/94567",
+				"type": "%s",
+				"revocationListIndex": 94567,
+				"revocationListCredential": "https://example.com/credentials/status/3"
+			}`, tt.typ)), &cs)
+			assert.NoError(t, err)
+			isIt, err := rl.IsRevoked(cs)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, isIt)
+				assert.NoError(t, ValidateCredentialStatus(cs))
+			} else {
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.Equal(t, tt.wantErr.Error(), ValidateCredentialStatus(cs).Error())
+			}
+		})
+	}
+	// new statuses use the spec spelling
+	_, csType := NewCredentialStatus(rl.ID, 1).TypeDef()
+	assert.Equal(t, "RevocationList2020Status", csType)
+}
+
+func TestNewRevocationListFromJSON_Purpose(t *testing.T) {
+	_, err := NewRevocationListFromJSON([]byte(`{"id":"c0","type":"RevocationList2020","statusPurpose":"expiration","encodedList":"eJzswDEBAAAAwiD7pzbGHhgAAAAAAAAAAAAAAAAAAACQ+wBAAAAB"}`))
+	assert.Error(t, err)
+	assert.Equal(t, "unsupported status purpose expiration, expected revocation or suspension", err.Error())
+}
+
+func TestRevocationList2020_Merge(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		a       []int
+		b       []int
+		bID     string
+		bSize   int
+		want    []int
+		wantErr error
+	}{
+		{
+			"PASS: disjoint revocations",
+			[]int{1, 2, 3},
+			[]int{100, 131071},
+			"c0",
+			16,
+			[]int{1, 2, 3, 100, 131071},
+			nil,
+		},
+		{
+			"PASS: overlapping revocations",
+			[]int{1, 2, 3},
+			[]int{2, 3, 4},
+			"c0",
+			16,
+			[]int{1, 2, 3, 4},
+			nil,
+		},
+		{
+			"FAIL: different list",
+			[]int{1},
+			[]int{2},
+			"c1",
+			16,
+			[]int{1},
+			fmt.Errorf("wrong revocation list, expected c0, got c1"),
+		},
+		{
+			"FAIL: different capacity",
+			[]int{1},
+			[]int{2},
+			"c0",
+			32,
+			[]int{1},
+			fmt.Errorf("revocation list capacity mismatch, expected 131072, got 262144"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, a.Revoke(tt.a...))
+			b, _ := NewRevocationList(tt.bID, tt.bSize)
+			assert.NoError(t, b.Revoke(tt.b...))
+			err := a.Merge(&b)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+			assert.Equal(t, tt.want, a.RevokedIndexes())
+			// the other list is not modified
+			assert.Equal(t, tt.b, b.RevokedIndexes())
+			// the encoded list is updated
+			expected, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, expected.Revoke(tt.want...))
+			assert.Equal(t, expected.EncodedList, a.EncodedList)
+		})
+	}
+}
+
+func TestRevocationList2020_Merge_Concurrent(t *testing.T) {
+	a, _ := NewRevocationList("c0", 16)
+	b, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, a.Revoke(1))
+	assert.NoError(t, b.Revoke(2))
+
+	// merging two lists into each other at the same time does not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, a.Merge(&b))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, b.Merge(&a))
+		}()
+		go func() {
+			defer wg.Done()
+			_, _, err := a.Diff(&b)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			b.Equal(&a)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{1, 2}, a.RevokedIndexes())
+	assert.Equal(t, []int{1, 2}, b.RevokedIndexes())
+	assert.NoError(t, a.Merge(&a))
+	assert.True(t, a.Equal(&a))
+}
+
+func TestRevocationList2020_Diff(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		before      []int
+		after       []int
+		prevID      string
+		wantRevoked []int
+		wantReset   []int
+		wantErr     error
+	}{
+		{
+			"PASS: no changes",
+			[]int{1, 2},
+			[]int{1, 2},
+			"c0",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"PASS: revocations and resets",
+			[]int{1, 2, 3, 1000},
+			[]int{2, 3, 4, 131071},
+			"c0",
+			[]int{4, 131071},
+			[]int{1, 1000},
+			nil,
+		},
+		{
+			"FAIL: different list",
+			nil,
+			nil,
+			"c1",
+			nil,
+			nil,
+			fmt.Errorf("wrong revocation list, expected c0, got c1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := NewRevocationList(tt.prevID, 16)
+			assert.NoError(t, before.Revoke(tt.before...))
+			after, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, after.Revoke(tt.after...))
+			revoked, reset, err := after.Diff(&before)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+			assert.Equal(t, tt.wantRevoked, revoked)
+			assert.Equal(t, tt.wantReset, reset)
+		})
+	}
+}
+
+func TestBitSet_Bounds(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		index   int
+		wantErr error
+	}{
+		{
+			"PASS: first index",
+			0,
+			nil,
+		},
+		{
+			"PASS: last index",
+			131071,
+			nil,
+		},
+		{
+			"FAIL: negative index",
+			-1,
+			fmt.Errorf("credential index out of range 0-131072: -1"),
+		},
+		{
+			"FAIL: index too large",
+			131072,
+			fmt.Errorf("credential index out of range 0-131072: 131072"),
+		},
+		{
+			"FAIL: index way too large",
+			1 << 40,
+			fmt.Errorf("credential index out of range 0-131072: %d", 1<<40),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs, _ := newBitSet(16)
+			err := bs.trySetBit(tt.index, true)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, bs.getBit(tt.index))
+				assert.Equal(t, 1, bs.count())
+				return
+			}
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantErr.Error(), err.Error())
+			// the low level accessors do not panic
+			assert.NotPanics(t, func() {
+				bs.setBit(tt.index, true)
+				assert.False(t, bs.getBit(tt.index))
+			})
+			assert.Equal(t, 0, bs.count())
+		})
+	}
+}
+
+func BenchmarkRevocationList2020_Update(b *testing.B) {
+	rl, _ := NewRevocationList("c0", 16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = rl.Revoke(i % rl.Capacity())
+	}
+}
+
+func TestRevocationList2020_UpdateSorted(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		revoke  []int
+		reset   []int
+		wantErr error
+	}{
+		{
+			"PASS: empty",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"PASS: sorted with duplicates",
+			[]int{0, 1, 1, 7, 8, 9, 1000, 131071},
+			[]int{1, 8, 131071},
+			nil,
+		},
+		{
+			"FAIL: not sorted",
+			[]int{10, 9},
+			nil,
+			fmt.Errorf("credential indexes must be sorted in ascending order"),
+		},
+		{
+			"FAIL: out of range",
+			[]int{10, 131072},
+			nil,
+			fmt.Errorf("credential index out of range 0-131072: 131072"),
+		},
+		{
+			"FAIL: negative",
+			[]int{-1, 10},
+			nil,
+			fmt.Errorf("credential index out of range 0-131072: -1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted, _ := NewRevocationList("c0", 16)
+			err := sorted.UpdateSorted(Revoke, tt.revoke...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.Equal(t, 0, sorted.RevokedCount())
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, sorted.UpdateSorted(Reset, tt.reset...))
+			// the result is the same of Update
+			unsorted, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, unsorted.Update(Revoke, tt.revoke...))
+			assert.NoError(t, unsorted.Update(Reset, tt.reset...))
+			assert.Equal(t, unsorted.BitSet(), sorted.BitSet())
+			assert.Equal(t, unsorted.EncodedList, sorted.EncodedList)
+		})
+	}
+}
+
+func BenchmarkRevocationList2020_BulkUpdate(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	random := r.Perm(16 * 1024 * 8)[:50000]
+	sorted := append([]int(nil), random...)
+	sort.Ints(sorted)
+
+	b.Run("random", func(b *testing.B) {
+		rl, _ := NewRevocationList("c0", 16)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = rl.Update(i%2 == 0, random...)
+		}
+	})
+	b.Run("sorted", func(b *testing.B) {
+		rl, _ := NewRevocationList("c0", 16)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = rl.UpdateSorted(i%2 == 0, sorted...)
+		}
+	})
+}
+
+func TestRevocationList2020_TryUpdate(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		revoke  []int
+		want    []int
+		wantErr error
+	}{
+		{
+			"PASS: all valid",
+			[]int{1, 2, 3},
+			[]int{1, 2, 3},
+			nil,
+		},
+		{
+			"FAIL: mixed valid and invalid",
+			[]int{-1, 1, 131072, 2, 200000},
+			[]int{1, 2},
+			fmt.Errorf("credential index out of range 0-131072: -1\n" +
+				"credential index out of range 0-131072: 131072\n" +
+				"credential index out of range 0-131072: 200000"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			err := rl.TryUpdate(Revoke, tt.revoke...)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			}
+			// the valid indexes are applied
+			assert.Equal(t, tt.want, rl.RevokedIndexes())
+			expected, _ := NewRevocationList("c0", 16)
+			assert.NoError(t, expected.Revoke(tt.want...))
+			assert.Equal(t, expected.EncodedList, rl.EncodedList)
+		})
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	other, _ := NewRevocationList("c1", 16)
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{
+			"FAIL: update out of range",
+			func() error { return rl.Update(Revoke, 131072) },
+			ErrIndexOutOfRange,
+		},
+		{
+			"FAIL: update range out of range",
+			func() error { return rl.RevokeRange(0, 131073) },
+			ErrIndexOutOfRange,
+		},
+		{
+			"FAIL: status out of range",
+			func() error {
+				_, err := rl.IsRevoked(NewCredentialStatus("c0", -1))
+				return err
+			},
+			ErrIndexOutOfRange,
+		},
+		{
+			"FAIL: status for another list",
+			func() error {
+				_, err := rl.IsRevoked(NewCredentialStatus("c1", 1))
+				return err
+			},
+			ErrWrongList,
+		},
+		{
+			"FAIL: merge another list",
+			func() error { return rl.Merge(&other) },
+			ErrWrongList,
+		},
+		{
+			"FAIL: status with unsupported type",
+			func() error {
+				_, err := rl.IsRevoked(CredentialStatusJSON{ID: "c0/1", Type: "Other", RevocationListCredential: "c0", RevocationListIndex: 1})
+				return err
+			},
+			ErrUnsupportedType,
+		},
+		{
+			"FAIL: list with unsupported type",
+			func() error {
+				_, err := NewRevocationListFromJSON([]byte(`{"id":"c0","type":"Other","encodedList":""}`))
+				return err
+			},
+			ErrUnsupportedType,
+		},
+		{
+			"FAIL: status with empty ID",
+			func() error {
+				_, err := rl.IsRevoked(CredentialStatusJSON{Type: TypeRevocationList2020Status, RevocationListCredential: "c0", RevocationListIndex: 1})
+				return err
+			},
+			ErrEmptyID,
+		},
+		{
+			"FAIL: list with empty ID",
+			func() error {
+				_, err := NewRevocationListFromJSON([]byte(`{"type":"RevocationList2020","encodedList":""}`))
+				return err
+			},
+			ErrEmptyID,
+		},
+		{
+			"FAIL: size too small",
+			func() error {
+				_, err := NewRevocationList("c0", 1)
+				return err
+			},
+			ErrSizeOutOfBounds,
+		},
+		{
+			"FAIL: resize too large",
+			func() error { return rl.Resize(129) },
+			ErrSizeOutOfBounds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestNewRevocationListWithBits(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		bits      int
+		wantBytes int
+		wantErr   error
+	}{
+		{
+			"PASS: not a multiple of 8",
+			200003,
+			25 * 1024,
+			nil,
+		},
+		{
+			"PASS: below the minimum size",
+			100000,
+			16 * 1024,
+			nil,
+		},
+		{
+			"FAIL: no bits",
+			0,
+			0,
+			fmt.Errorf("size out of bounds: must be between 1 and 1048576 bits, got 0"),
+		},
+		{
+			"FAIL: too many bits",
+			1048577,
+			0,
+			fmt.Errorf("size out of bounds: must be between 1 and 1048576 bits, got 1048577"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListWithBits("c0", tt.bits)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.True(t, errors.Is(err, ErrSizeOutOfBounds))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.bits, rl.Capacity())
+			assert.Equal(t, tt.wantBytes, len(rl.BitSet()))
+			assert.Len(t, rl.ToBoolSlice(), tt.bits)
+			// the list can be loaded back with its capacity
+			data, _ := rl.GetBytes()
+			got, err := NewRevocationListFromJSON(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.bits, got.Capacity())
+			// the top index is addressable
+			assert.NoError(t, rl.Revoke(tt.bits-1))
+			isIt, err := rl.IsRevoked(NewCredentialStatus("c0", tt.bits-1))
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+			// the indexes beyond the capacity are not
+			err = rl.Revoke(tt.bits)
+			assert.True(t, errors.Is(err, ErrIndexOutOfRange))
+			assert.Equal(t, fmt.Sprintf("credential index out of range 0-%d: %d", tt.bits, tt.bits), err.Error())
+			_, err = rl.IsRevoked(NewCredentialStatus("c0", tt.bits))
+			assert.True(t, errors.Is(err, ErrIndexOutOfRange))
+			assert.Error(t, rl.RevokeRange(0, tt.bits+1))
+			// revoking all the credentials does not touch the bits beyond the capacity
+			assert.NoError(t, rl.RevokeAll())
+			assert.Equal(t, tt.bits, rl.RevokedCount())
+			_, err = rl.FindFirstAvailable()
+			assert.Error(t, err)
+		})
+	}
+
+	// the capacity is serialized only when it is smaller than the bit set
+	rl, _ := NewRevocationListWithBits("c0", 100000)
+	data, _ := rl.GetBytes()
+	assert.Equal(t, fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s","capacity":100000,"schemaVersion":1}`, rl.EncodedList), string(data))
+	rl, _ = NewRevocationListWithBits("c0", 16*1024*8)
+	data, _ = rl.GetBytes()
+	assert.NotContains(t, string(data), "capacity")
+	// a capacity larger than the bit set is rejected
+	_, err := NewRevocationListFromJSON([]byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s","capacity":131073}`, rl.EncodedList)))
+	assert.True(t, errors.Is(err, ErrSizeOutOfBounds))
+	assert.EqualError(t, err, "size out of bounds: capacity must be between 1 and 131072, got 131073")
+	// and so is a list with credentials revoked beyond the capacity
+	full, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, full.Revoke(160))
+	data, _ = full.GetBytes()
+	_, err = NewRevocationListFromJSON(bytes.Replace(data, []byte(`}`), []byte(`,"capacity":100}`), 1))
+	assert.ErrorIs(t, err, ErrCorruptEncodedList)
+	assert.EqualError(t, err, "revocation list c0: corrupt encoded list: index 160 is revoked beyond the capacity 100")
+}
+
+func TestRevocationList2020_IsRevokedConstantTime(t *testing.T) {
+	rl, _ := NewRevocationListWithBits("c0", 200003)
+	var revoked []int
+	for i := 0; i < rl.Capacity(); i += 7 {
+		revoked = append(revoked, i)
+	}
+	assert.NoError(t, rl.UpdateSorted(Revoke, revoked...))
+	// cross check with IsRevoked on a sample of the list and around its bounds
+	indexes := []int{-1, 0, 7, rl.Capacity() - 1, rl.Capacity()}
+	for i := 1; i < rl.Capacity(); i += 997 {
+		indexes = append(indexes, i, i+1)
+	}
+	for _, i := range indexes {
+		cs := NewCredentialStatus("c0", i)
+		want, wantErr := rl.IsRevoked(cs)
+		got, err := rl.IsRevokedConstantTime(cs)
+		if !assert.Equal(t, wantErr, err, "index %d", i) || !assert.Equal(t, want, got, "index %d", i) {
+			return
+		}
+	}
+	_, err := rl.IsRevokedConstantTime(NewCredentialStatus("c1", 0))
+	assert.True(t, errors.Is(err, ErrWrongList))
+}
+
+func BenchmarkRevocationList2020_IsRevokedConstantTime(b *testing.B) {
+	rl, _ := NewRevocationList("c0", 16)
+	_ = rl.Revoke(1)
+	revoked, valid := NewCredentialStatus("c0", 1), NewCredentialStatus("c0", 2)
+
+	b.Run("revoked", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = rl.IsRevokedConstantTime(revoked)
+		}
+	})
+	b.Run("valid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = rl.IsRevokedConstantTime(valid)
+		}
+	})
+}
+
+func TestRevocationList2020_AllocateRandom(t *testing.T) {
+	rl, _ := NewRevocationListWithBits("c0", 100003)
+	assert.NoError(t, rl.RevokeRange(0, 50000))
+
+	seen := make(map[int]bool)
+	for i := 0; i < 5000; i++ {
+		index, err := rl.AllocateRandom()
+		assert.NoError(t, err)
+		assert.False(t, seen[index], "duplicate index %d", index)
+		assert.True(t, index >= 50000 && index < rl.Capacity(), "index %d", index)
+		seen[index] = true
+	}
+
+	// exhaust a small list
+	rl, _ = NewRevocationListWithBits("c0", 10)
+	assert.NoError(t, rl.Revoke(0, 2, 4, 6, 8))
+	var got []int
+	for i := 0; i < 5; i++ {
+		index, err := rl.AllocateRandom()
+		assert.NoError(t, err)
+		got = append(got, index)
+	}
+	sort.Ints(got)
+	assert.Equal(t, []int{1, 3, 5, 7, 9}, got)
+	_, err := rl.AllocateRandom()
+	assert.EqualError(t, err, "no available credential index")
+}
+
+func TestRevocationList2020_ToHex(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(0, 9, 131071))
+
+	h := rl.ToHex()
+	assert.Len(t, h, 2*16*1024)
+	assert.True(t, strings.HasPrefix(h, "0102"))
+	assert.True(t, strings.HasSuffix(h, "80"))
+
+	got, err := NewRevocationListFromHex("c0", h)
+	assert.NoError(t, err)
+	assert.True(t, rl.Equal(&got))
+	assert.Equal(t, rl.EncodedList, got.EncodedList)
+
+	_, err = NewRevocationListFromHex("c0", "zz")
+	assert.Error(t, err)
+	_, err = NewRevocationListFromHex("c0", "0102")
+	assert.True(t, errors.Is(err, ErrSizeOutOfBounds))
+	// the bit set must be a whole number of KB
+	_, err = NewRevocationListFromHex("c0", h+"00")
+	assert.ErrorIs(t, err, ErrCorruptEncodedList)
+	assert.EqualError(t, err, "corrupt encoded list: decompressed to 16385 bytes, not a whole number of KB")
+	// and not smaller than the minimum size
+	_, err = NewRevocationListFromHex("c0", h, WithMinimumSize(32))
+	assert.EqualError(t, err, "size out of bounds: must be at least 32, got 16")
+}
+
+func TestRevocationList2020_IsDirty(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1))
+	assert.False(t, rl.IsDirty())
+
+	// the bit set returned by BitSet is a copy
+	rl.BitSet()[0] |= 0x04
+	assert.False(t, rl.IsDirty())
+	assert.Equal(t, []int{1}, rl.RevokedIndexes())
+
+	// an encoded list replaced by hand is stale until the list is synced
+	other, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, other.Revoke(1, 2))
+	rl.EncodedList = other.EncodedList
+	assert.True(t, rl.IsDirty())
+	assert.True(t, rl.IsDirty())
+	assert.NoError(t, rl.Sync())
+	assert.False(t, rl.IsDirty())
+	assert.Equal(t, []int{1}, rl.RevokedIndexes())
+
+	got, err := NewRevocationListFromJSON([]byte(fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":%q}`, rl.EncodedList)))
+	assert.NoError(t, err)
+	assert.False(t, got.IsDirty())
+
+	// an encoded list that cannot be decoded is stale
+	rl.EncodedList = "not base64"
+	assert.True(t, rl.IsDirty())
+}
+
+func TestNewRevocationListWithIssuer(t *testing.T) {
+	issued := time.Date(2020, 4, 5, 14, 27, 40, 123, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		name         string
+		issuer       string
+		issuanceDate time.Time
+		wantJSON     []string
+		wantDate     time.Time
+	}{
+		{
+			"PASS: with issuer and issuance date",
+			"did:example:12345",
+			issued,
+			[]string{`"issuer":"did:example:12345"`, `"issuanceDate":"2020-04-05T12:27:40Z"`},
+			time.Date(2020, 4, 5, 12, 27, 40, 0, time.UTC),
+		},
+		{
+			"PASS: without issuer and issuance date",
+			"",
+			time.Time{},
+			nil,
+			time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListWithIssuer("c0", 16, tt.issuer, tt.issuanceDate)
+			assert.NoError(t, err)
+			data, err := rl.GetBytes()
+			assert.NoError(t, err)
+			if tt.wantJSON == nil {
+				assert.NotContains(t, string(data), "issuer")
+				assert.NotContains(t, string(data), "issuanceDate")
+			}
+			for _, want := range tt.wantJSON {
+				assert.Contains(t, string(data), want)
+			}
+			got, err := NewRevocationListFromJSON(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.issuer, got.Issuer)
+			assert.True(t, tt.wantDate.Equal(got.IssuanceDate), "got %v", got.IssuanceDate)
+			assert.True(t, rl.Equal(&got))
+		})
+	}
+}
+
+func TestRevocationList2020_IsExpired(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		validFrom  time.Time
+		validUntil time.Time
+		want       bool
+	}{
+		{
+			"PASS: no validity window",
+			time.Time{},
+			time.Time{},
+			false,
+		},
+		{
+			"PASS: still valid",
+			now.AddDate(0, -1, 0),
+			now.AddDate(0, 1, 0),
+			false,
+		},
+		{
+			"PASS: window has passed",
+			now.AddDate(0, -2, 0),
+			now.AddDate(0, -1, 0),
+			true,
+		},
+		{
+			"PASS: window not started",
+			now.AddDate(0, 1, 0),
+			time.Time{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			rl.ValidFrom, rl.ValidUntil = tt.validFrom, tt.validUntil
+			assert.Equal(t, tt.want, rl.IsExpired(now))
+
+			data, err := rl.GetBytes()
+			assert.NoError(t, err)
+			for field, v := range map[string]time.Time{"validFrom": tt.validFrom, "validUntil": tt.validUntil} {
+				if v.IsZero() {
+					assert.NotContains(t, string(data), field)
+				} else {
+					assert.Contains(t, string(data), fmt.Sprintf("%q:%q", field, v.Format(time.RFC3339)))
+				}
+			}
+			got, err := NewRevocationListFromJSON(data)
+			assert.NoError(t, err)
+			assert.True(t, tt.validFrom.Equal(got.ValidFrom))
+			assert.True(t, tt.validUntil.Equal(got.ValidUntil))
+			assert.Equal(t, tt.want, got.IsExpired(now))
+		})
+	}
+}
+
+func TestKBSizeForCapacity(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		n       int
+		opts    []Option
+		want    int
+		wantErr error
+	}{
+		{"PASS: no credentials", 0, nil, 16, nil},
+		{"PASS: below the minimum size", 100000, nil, 16, nil},
+		{"PASS: minimum size", 131072, nil, 16, nil},
+		{"PASS: above the minimum size", 131073, nil, 17, nil},
+		{"PASS: maximum size", 1048576, nil, 128, nil},
+		{"PASS: custom minimum size", 1, []Option{WithMinimumSize(64)}, 64, nil},
+		{"FAIL: above the maximum size", 1048577, nil, 0, fmt.Errorf("size out of bounds: capacity must be between 0 and 1048576, got 1048577")},
+		{"FAIL: negative capacity", -1, nil, 0, fmt.Errorf("size out of bounds: capacity must be between 0 and 1048576, got -1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KBSizeForCapacity(tt.n, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.True(t, errors.Is(err, ErrSizeOutOfBounds))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			rl, err := NewRevocationList("c0", got, tt.opts...)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, rl.Capacity(), tt.n)
+		})
+	}
+}
+
+func TestRevocationList2020_ContentHash(t *testing.T) {
+	fast, _ := NewRevocationList("c0", 16, WithCompressionLevel(zlib.BestSpeed))
+	best, _ := NewRevocationList("c0", 16, WithCompressionLevel(zlib.BestCompression), WithURLEncoding())
+	other, _ := NewRevocationList("c1", 16)
+	for _, rl := range []*RevocationList2020{&fast, &best, &other} {
+		assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	}
+	assert.NotEqual(t, fast.EncodedList, best.EncodedList)
+	assert.Equal(t, fast.ContentHash(), best.ContentHash())
+	assert.NotEqual(t, fast.ContentHash(), other.ContentHash())
+
+	h := fast.ContentHash()
+	assert.NoError(t, fast.Revoke(2))
+	assert.NotEqual(t, h, fast.ContentHash())
+	assert.NoError(t, fast.Reset(2))
+	assert.Equal(t, h, fast.ContentHash())
+}
+
+func TestNewRevocationListFromJSON_Corrupt(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+	b, _ := base64.StdEncoding.DecodeString(rl.EncodedList)
+
+	tests := []struct {
+		name        string
+		encodedList string
+		wantErr     string
+	}{
+		{
+			"FAIL: truncated zlib stream",
+			base64.StdEncoding.EncodeToString(b[:len(b)/2]),
+			"revocation list c0: corrupt encoded list: unexpected EOF",
+		},
+		{
+			"FAIL: corrupt zlib checksum",
+			base64.StdEncoding.EncodeToString(append(append([]byte(nil), b[:len(b)-1]...), b[len(b)-1]^0xff)),
+			"revocation list c0: corrupt encoded list: zlib: invalid checksum",
+		},
+		{
+			"FAIL: not base64",
+			"not-base64!",
+			"revocation list c0: corrupt encoded list: illegal base64 data at input byte 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":%q}`, tt.encodedList)
+			_, err := NewRevocationListFromJSON([]byte(data))
+			assert.Error(t, err)
+			assert.ErrorIs(t, err, ErrCorruptEncodedList)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func TestRevocationList2020_CapacityRemaining(t *testing.T) {
+	rl, _ := NewRevocationListWithBits("c0", 100003)
+	assert.Equal(t, 100003, rl.CapacityRemaining())
+	assert.NoError(t, rl.Revoke(0, 1, 2, 100002))
+	assert.NoError(t, rl.RevokeRange(1000, 2000))
+	assert.Equal(t, 100003-1004, rl.CapacityRemaining())
+	assert.NoError(t, rl.RevokeAll())
+	assert.Equal(t, 0, rl.CapacityRemaining())
+}
+
+func TestRevocationList2020_IssueStatus(t *testing.T) {
+	rl, _ := NewRevocationListWithBits("c0", 20)
+	assert.NoError(t, rl.Revoke(1, 3))
+	random, err := rl.AllocateRandom()
+	assert.NoError(t, err)
+
+	// successive calls skip revoked and already allocated indexes
+	seen := map[int]bool{1: true, 3: true, random: true}
+	for i := 0; i < 17; i++ {
+		cs, err := rl.IssueStatus()
+		assert.NoError(t, err)
+		list, index := cs.Coordinates()
+		assert.Equal(t, "c0", list)
+		assert.False(t, seen[index], "duplicate index %d", index)
+		seen[index] = true
+		// the credential is not revoked
+		isIt, err := rl.IsRevoked(cs)
+		assert.NoError(t, err)
+		assert.False(t, isIt)
+	}
+	assert.Len(t, seen, 20)
+	_, err = rl.IssueStatus()
+	assert.EqualError(t, err, "no available credential index")
+
+	// the status carries the purpose of the list, unless it is the implied revocation
+	revocations, _ := NewRevocationList("c0", 16)
+	status, err := revocations.IssueStatus()
+	assert.NoError(t, err)
+	assert.Empty(t, status.(CredentialStatusJSON).StatusPurpose)
+	suspensions, _ := NewRevocationList("c0", 16, WithStatusPurpose(PurposeSuspension))
+	status, err = suspensions.IssueStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, PurposeSuspension, status.(CredentialStatusJSON).StatusPurpose)
+	_, index := status.Coordinates()
+	assert.NoError(t, suspensions.Revoke(index))
+	isIt, err := suspensions.IsRevoked(status)
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+}
+
+func TestRevocationList2020_Summary(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 32)
+	assert.NoError(t, rl.RevokeRange(0, 65536))
+
+	data, err := json.Marshal(rl.Summary())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "https://example.com/credentials/status/3",
+		"type": "RevocationList2020",
+		"capacity": 262144,
+		"revoked": 65536,
+		"sizeKB": 32,
+		"fillRatio": 0.25
+	}`, string(data))
+
+	empty := RevocationList2020{}
+	assert.Equal(t, Summary{}, empty.Summary())
+}
+
+func TestNewRevocationListWithRevoked(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		kbSize  int
+		revoked []int
+		want    []int
+		wantErr error
+	}{
+		{
+			"PASS: revoked indexes",
+			16,
+			[]int{131071, 10, 1000, 10},
+			[]int{10, 1000, 131071},
+			nil,
+		},
+		{
+			"PASS: no revoked indexes",
+			16,
+			nil,
+			[]int{},
+			nil,
+		},
+		{
+			"FAIL: index out of range",
+			16,
+			[]int{10, 131072},
+			nil,
+			fmt.Errorf("credential index out of range 0-131072: 131072"),
+		},
+		{
+			"FAIL: size out of bounds",
+			8,
+			[]int{10},
+			nil,
+			fmt.Errorf("size out of bounds: must be between 16 and 128, got 8"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListWithRevoked("c0", tt.kbSize, tt.revoked)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rl.RevokedIndexes())
+			expected, _ := NewRevocationList("c0", tt.kbSize)
+			assert.NoError(t, expected.Revoke(tt.revoked...))
+			assert.Equal(t, expected.EncodedList, rl.EncodedList)
+			assert.False(t, rl.IsDirty())
+		})
+	}
+}
+
+func TestRevocationList2020_Owns(t *testing.T) {
+	rl, _ := NewRevocationListWithBits("https://example.com/credentials/status/3", 100003)
+
+	tests := []struct {
+		name   string
+		status CredentialStatus
+		want   bool
+	}{
+		{"PASS: first index", NewCredentialStatus(rl.ID, 0), true},
+		{"PASS: last index", NewCredentialStatus(rl.ID, 100002), true},
+		{"FAIL: other list", NewCredentialStatus("https://example.com/credentials/status/4", 1), false},
+		{"FAIL: beyond the capacity", NewCredentialStatus(rl.ID, 100003), false},
+		{"FAIL: negative index", NewCredentialStatus(rl.ID, -1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rl.Owns(tt.status))
+		})
+	}
+}
+
+func TestRevocationList2020_MarshalJSON_Stable(t *testing.T) {
+	issued := time.Date(2020, 4, 5, 14, 27, 40, 0, time.UTC)
+	rl, _ := NewRevocationListWithIssuer("c0", 16, "did:example:12345", issued, WithStatusPurpose(PurposeSuspension))
+	rl.ValidFrom, rl.ValidUntil = issued, issued.AddDate(1, 0, 0)
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+
+	first, err := json.Marshal(&rl)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := rl.GetBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+	// the same state marshals the same, regardless of how it was built
+	other, err := NewRevocationListFromJSON(first)
+	assert.NoError(t, err)
+	clone := other.Clone()
+	again, err := json.Marshal(&clone)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	// the fields have a fixed order
+	fields := []string{"id", "type", "statusPurpose", "issuer", "issuanceDate", "validFrom", "validUntil", "encodedList"}
+	last := -1
+	for _, f := range fields {
+		i := bytes.Index(first, []byte(fmt.Sprintf("%q:", f)))
+		assert.Greater(t, i, last, f)
+		last = i
+	}
+}
+
+func TestParseCredentialStatus(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []CredentialStatus
+		wantErr bool
+	}{
+		{
+			"PASS: single status",
+			`{
+				"id": "https://example.com/credentials/status/3/94567",
+				"type": "RevocationList2020Status",
+				"revocationListIndex": 94567,
+				"revocationListCredential": "https://example.com/credentials/status/3"
+			}`,
+			[]CredentialStatus{NewCredentialStatus("https://example.com/credentials/status/3", 94567)},
+			false,
+		},
+		{
+			"PASS: array of statuses",
+			`[{
+				"id": "https://example.com/credentials/status/3/94567",
+				"type": "RevocationList2020Status",
+				"revocationListIndex": 94567,
+				"revocationListCredential": "https://example.com/credentials/status/3"
+			}, {
+				"id": "https://example.com/credentials/status/4/10",
+				"type": "RevocationList2020Status",
+				"statusPurpose": "suspension",
+				"revocationListIndex": 10,
+				"revocationListCredential": "https://example.com/credentials/status/4"
+			}]`,
+			[]CredentialStatus{
+				NewCredentialStatus("https://example.com/credentials/status/3", 94567),
+				CredentialStatusJSON{
+					ID:                       "https://example.com/credentials/status/4/10",
+					Type:                     TypeRevocationList2020Status,
+					RevocationListIndex:      10,
+					RevocationListCredential: "https://example.com/credentials/status/4",
+					StatusPurpose:            PurposeSuspension,
+				},
+			},
+			false,
+		},
+		{
+			"FAIL: not a status",
+			`"https://example.com/credentials/status/3"`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCredentialStatus([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRevocationList2020_IsRevokedAny(t *testing.T) {
+	revocations, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	suspensions, _ := NewRevocationList("https://example.com/credentials/status/3", 16, WithStatusPurpose(PurposeSuspension))
+	assert.NoError(t, suspensions.Revoke(10))
+
+	statuses, err := ParseCredentialStatus([]byte(`[{
+		"id": "https://example.com/credentials/status/3/94567",
+		"type": "RevocationList2020Status",
+		"statusPurpose": "revocation",
+		"revocationListIndex": 94567,
+		"revocationListCredential": "https://example.com/credentials/status/3"
+	}, {
+		"id": "https://example.com/credentials/status/3/10",
+		"type": "RevocationList2020Status",
+		"statusPurpose": "suspension",
+		"revocationListIndex": 10,
+		"revocationListCredential": "https://example.com/credentials/status/3"
+	}]`))
+	assert.NoError(t, err)
+
+	// only the entries with the purpose of the list are checked
+	isIt, err := revocations.IsRevokedAny(statuses)
+	assert.NoError(t, err)
+	assert.False(t, isIt)
+	isIt, err = suspensions.IsRevokedAny(statuses)
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+
+	// the statuses issued by a suspension list are checked against it
+	issuer, _ := NewRevocationList("c0", 16, WithStatusPurpose(PurposeSuspension))
+	issued, err := issuer.IssueStatus()
+	assert.NoError(t, err)
+	assert.NoError(t, issuer.Revoke(0))
+	isIt, err = issuer.IsRevokedAny([]CredentialStatus{issued})
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+
+	other, _ := NewRevocationList("https://example.com/credentials/status/4", 16)
+	_, err = other.IsRevokedAny(statuses)
+	assert.ErrorIs(t, err, ErrWrongList)
+	assert.EqualError(t, err, "wrong revocation list, no credential status for https://example.com/credentials/status/4 with purpose revocation")
+
+	// the IDs are compared with the normalizer of the list
+	normalized, _ := NewRevocationList("https://example.com/credentials/status/3/", 16, WithStatusPurpose(PurposeSuspension), WithIDNormalizer(NormalizeID))
+	assert.NoError(t, normalized.Revoke(10))
+	isIt, err = normalized.IsRevokedAny(statuses)
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+	strict, _ := NewRevocationList("https://example.com/credentials/status/3/", 16, WithStatusPurpose(PurposeSuspension))
+	_, err = strict.IsRevokedAny(statuses)
+	assert.ErrorIs(t, err, ErrWrongList)
+}
+
+func TestRevocationList2020_Snapshot(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 1000))
+	snapshot := rl.Snapshot()
+	encodedList := rl.EncodedList
+
+	assert.NoError(t, rl.Revoke(2, 131071))
+	assert.NoError(t, rl.Reset(1))
+	// the snapshot is a copy
+	assert.Equal(t, byte(0x02), snapshot[0])
+
+	assert.NoError(t, rl.RestoreSnapshot(snapshot))
+	assert.Equal(t, []int{1, 1000}, rl.RevokedIndexes())
+	assert.Equal(t, encodedList, rl.EncodedList)
+	// the restored list does not share the snapshot
+	snapshot[0] = 0xff
+	assert.Equal(t, []int{1, 1000}, rl.RevokedIndexes())
+
+	assert.EqualError(t, rl.RestoreSnapshot(make([]byte, 10)), "snapshot size mismatch, expected 16384 bytes, got 10")
+	assert.Equal(t, []int{1, 1000}, rl.RevokedIndexes())
+}
+
+func TestRevocationList2020_UpdateChanged(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 3, 5, 7))
+
+	tests := []struct {
+		name    string
+		action  bool
+		indexes []int
+		want    int
+		wantErr bool
+	}{
+		{"PASS: half already revoked", Revoke, []int{1, 2, 3, 4, 5, 6, 7, 8}, 4, false},
+		{"PASS: all already revoked", Revoke, []int{1, 2, 3}, 0, false},
+		{"PASS: repeated indexes", Reset, []int{1, 1, 2, 2, 100}, 2, false},
+		{"FAIL: out of range", Reset, []int{3, 131072}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := rl.UpdateChanged(tt.action, tt.indexes...)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, changed)
+		})
+	}
+	assert.Equal(t, []int{3, 4, 5, 6, 7, 8}, rl.RevokedIndexes())
+}
+
+func TestRevocationList2020_Toggle(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 2, 3))
+	original := rl.Clone()
+
+	assert.NoError(t, rl.Toggle(2, 3, 4, 131071))
+	assert.Equal(t, []int{1, 4, 131071}, rl.RevokedIndexes())
+	assert.False(t, rl.IsDirty())
+
+	// toggling again restores the original state
+	assert.NoError(t, rl.Toggle(2, 3, 4, 131071))
+	assert.True(t, original.Equal(&rl))
+	assert.Equal(t, original.EncodedList, rl.EncodedList)
+
+	// a repeated index is flipped twice
+	assert.NoError(t, rl.Toggle(5, 5))
+	assert.True(t, original.Equal(&rl))
+
+	assert.EqualError(t, rl.Toggle(6, 131072), "credential index out of range 0-131072: 131072")
+	assert.True(t, original.Equal(&rl))
+}
+
+func TestValidateEncodedList(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 32)
+	assert.NoError(t, rl.Revoke(1, 1000))
+	var big bytes.Buffer
+	zw := zlib.NewWriter(&big)
+	_, _ = zw.Write(make([]byte, 1024*1024))
+	_ = zw.Close()
+
+	tests := []struct {
+		name        string
+		encodedList string
+		want        int
+		wantErr     string
+	}{
+		{
+			"PASS: valid list",
+			rl.EncodedList,
+			32,
+			"",
+		},
+		{
+			"FAIL: not base64",
+			"not-base64!",
+			0,
+			"corrupt encoded list: illegal base64 data at input byte 3",
+		},
+		{
+			"FAIL: not zlib",
+			base64.StdEncoding.EncodeToString([]byte("not a compressed list")),
+			0,
+			"corrupt encoded list: unknown compression, use WithCompression(Uncompressed) to read uncompressed lists",
+		},
+		{
+			"FAIL: truncated zlib",
+			rl.EncodedList[:len(rl.EncodedList)/2],
+			0,
+			"corrupt encoded list: unexpected EOF",
+		},
+		{
+			"FAIL: oversized",
+			base64.StdEncoding.EncodeToString(big.Bytes()),
+			0,
+			"size out of bounds: must be between 16 and 128, got more than 128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateEncodedList(tt.encodedList)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// packed empty lists are not read as uncompressed
+	empty, _ := NewRevocationList("c1", 16)
+	_, err := ValidateEncodedList(empty.EncodedList, WithCompression(Uncompressed))
+	assert.ErrorIs(t, err, ErrSizeOutOfBounds)
+}
+
+func TestRevocationList2020_HasRevocations(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.False(t, rl.HasRevocations())
+	assert.NoError(t, rl.Revoke(131071))
+	assert.True(t, rl.HasRevocations())
+	assert.NoError(t, rl.Reset(131071))
+	assert.False(t, rl.HasRevocations())
+
+	empty := RevocationList2020{}
+	assert.False(t, empty.HasRevocations())
+}
+
+func TestRevocationList2020_StatusOf(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		purpose string
+		index   int
+		want    Status
+		wantErr bool
+	}{
+		{"PASS: active in a revocation list", PurposeRevocation, 2, StatusActive, false},
+		{"PASS: revoked", PurposeRevocation, 1, StatusRevoked, false},
+		{"PASS: active in a suspension list", PurposeSuspension, 2, StatusActive, false},
+		{"PASS: suspended", PurposeSuspension, 1, StatusSuspended, false},
+		{"FAIL: out of range", PurposeRevocation, 131072, StatusActive, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16, WithStatusPurpose(tt.purpose))
+			assert.NoError(t, rl.Revoke(1))
+			got, err := rl.StatusOf(NewCredentialStatus("c0", tt.index))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrIndexOutOfRange)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.Equal(t, "suspended", StatusSuspended.String())
+	assert.Equal(t, "Status(7)", Status(7).String())
+}
+
+func TestParseCredentialStatusID(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		id        string
+		wantList  string
+		wantIndex int
+		wantErr   error
+	}{
+		{"PASS: url", "https://example.com/credentials/status/3/94567", "https://example.com/credentials/status/3", 94567, nil},
+		{"PASS: simple id", "c0/0", "c0", 0, nil},
+		{"FAIL: no index", "https://example.com/credentials/status/3/", "", 0, fmt.Errorf("malformed credential status ID https://example.com/credentials/status/3/, the index must be a non negative integer")},
+		{"FAIL: not a number", "https://example.com/credentials/status/three", "", 0, fmt.Errorf("malformed credential status ID https://example.com/credentials/status/three, the index must be a non negative integer")},
+		{"FAIL: negative index", "c0/-1", "", 0, fmt.Errorf("malformed credential status ID c0/-1, the index must be a non negative integer")},
+		{"FAIL: no list", "/1", "", 0, fmt.Errorf("malformed credential status ID /1, expected list/index")},
+		{"FAIL: no separator", "94567", "", 0, fmt.Errorf("malformed credential status ID 94567, expected list/index")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, index, err := ParseCredentialStatusID(tt.id)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantList, list)
+			assert.Equal(t, tt.wantIndex, index)
+		})
+	}
+
+	// legacy statuses carrying only the ID are checked using the coordinates in the ID
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(94567))
+	isIt, err := rl.IsRevoked(CredentialStatusJSON{
+		ID:   "https://example.com/credentials/status/3/94567",
+		Type: TypeRevocationList2020Status,
+	})
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+}
+
+func TestRevocationList2020_MarshalBinary(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+	assert.NoError(t, rl.Revoke(0, 10, 94567, 131071))
+
+	var m encoding.BinaryMarshaler = &rl
+	data, err := m.MarshalBinary()
+	assert.NoError(t, err)
+	// the compressed bit set is much smaller than the JSON
+	js, _ := json.Marshal(&rl)
+	assert.Less(t, len(data), len(js))
+
+	var got RevocationList2020
+	var u encoding.BinaryUnmarshaler = &got
+	assert.NoError(t, u.UnmarshalBinary(data))
+	assert.True(t, rl.Equal(&got))
+	assert.Equal(t, rl.EncodedList, got.EncodedList)
+	assert.Equal(t, []int{0, 10, 94567, 131071}, got.RevokedIndexes())
+	assert.Equal(t, PurposeRevocation, got.Purpose)
+
+	// the metadata of the list is preserved
+	issued := time.Date(2020, 4, 5, 14, 27, 40, 0, time.UTC)
+	sl, _ := NewRevocationListWithBits("https://example.com/credentials/status/4", 100000, WithStatusPurpose(PurposeSuspension), WithCompression(Uncompressed))
+	sl.Issuer, sl.IssuanceDate, sl.ValidUntil = "did:example:12345", issued, issued.AddDate(1, 0, 0)
+	assert.NoError(t, sl.Revoke(3, 8, 10, 11, 12))
+	sData, err := sl.MarshalBinary()
+	assert.NoError(t, err)
+	got = RevocationList2020{}
+	assert.NoError(t, got.UnmarshalBinary(sData))
+	assert.Equal(t, PurposeSuspension, got.Purpose)
+	assert.Equal(t, "did:example:12345", got.Issuer)
+	assert.Equal(t, issued, got.IssuanceDate)
+	assert.True(t, got.ValidFrom.IsZero())
+	assert.Equal(t, issued.AddDate(1, 0, 0), got.ValidUntil)
+	assert.Equal(t, uint64(1), got.Revision())
+	assert.Equal(t, 100000, got.Capacity())
+	assert.Equal(t, Uncompressed, got.opts.compression)
+	assert.Equal(t, []int{3, 8, 10, 11, 12}, got.RevokedIndexes())
+	assert.Equal(t, sl.EncodedList, got.EncodedList)
+
+	// the ID, prefixed by its length, follows the version byte
+	idLen := len(rl.ID) + 1
+	// a list with ID x, no purpose, issuer and dates, and zero revision and capacity
+	header := []byte{1, 1, 'x', 0, 0, 0, 0, 0, 0, 0}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"FAIL: empty", nil, fmt.Errorf("malformed binary revocation list")},
+		{"FAIL: unsupported version", append([]byte{2}, data[1:]...), fmt.Errorf("unsupported binary revocation list version 2, expected 1")},
+		{"FAIL: truncated ID", data[:10], fmt.Errorf("malformed binary revocation list")},
+		{"FAIL: empty ID", append([]byte{1, 0}, data[1+idLen:]...), fmt.Errorf("revocation list ID is empty")},
+		{"FAIL: missing compression", header, fmt.Errorf("malformed binary revocation list")},
+		{"FAIL: unsupported compression", append(header, 99), fmt.Errorf("unsupported compression 99")},
+		{"FAIL: invalid date", []byte{1, 1, 'x', 0, 0, 5, 'n', 'e', 'v', 'e', 'r', 0, 0, 0, 0, 0}, fmt.Errorf(`parsing time "never" as "2006-01-02T15:04:05Z07:00": cannot parse "never" as "2006"`)},
+		{"FAIL: empty bit set", append(header, byte(Zlib)), ErrCorruptEncodedList},
+		{"FAIL: truncated list", data[:len(data)-4], ErrCorruptEncodedList},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rl RevocationList2020
+			err := rl.UnmarshalBinary(tt.data)
+			if errors.Is(tt.wantErr, ErrCorruptEncodedList) {
+				assert.ErrorIs(t, err, ErrCorruptEncodedList)
+			} else {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_ShrinkToFit(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		kbSize int
+		revoke []int
+		opts   []Option
+		want   int
+	}{
+		{"PASS: revocations in the first kb", 64, []int{0, 100, 8191}, nil, 16},
+		{"PASS: empty list", 64, []int{}, nil, 16},
+		{"PASS: revocations in the 20th kb", 64, []int{1, 20*8192 - 1}, nil, 20},
+		{"PASS: revocations in the last kb", 64, []int{64*8192 - 1}, nil, 64},
+		{"PASS: never below the minimum size", 64, []int{1}, []Option{WithMinimumSize(32)}, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationList("c0", tt.kbSize, tt.opts...)
+			assert.NoError(t, err)
+			assert.NoError(t, rl.UpdateSorted(Revoke, tt.revoke...))
+			assert.NoError(t, rl.ShrinkToFit())
+			assert.Equal(t, tt.want, rl.Size())
+			assert.Len(t, rl.BitSet(), tt.want*1024)
+			assert.Equal(t, tt.revoke, rl.RevokedIndexes())
+			assert.False(t, rl.IsDirty())
+		})
+	}
+}
+
+func TestBitLocation(t *testing.T) {
+	tests := []struct {
+		index      int
+		wantOffset int
+		wantMask   uint8
+	}{
+		{0, 0, 0x01},
+		{7, 0, 0x80},
+		{15, 1, 0x80},
+		{94567, 11820, 0x80},
+		{131071, 16383, 0x80},
+	}
+	for _, tt := range tests {
+		offset, mask, err := BitLocation(tt.index)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.wantOffset, offset, "index %d", tt.index)
+		assert.Equal(t, tt.wantMask, mask, "index %d", tt.index)
+	}
+	_, _, err := BitLocation(-1)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+	assert.EqualError(t, err, "credential index out of range: -1")
+
+	bs, _ := newBitSet(16)
+	for i := 0; i < 4096; i += 3 {
+		offset, mask, _ := BitLocation(i)
+		// setting the bit sets exactly the masked bit of the byte
+		bs.setBit(i, true)
+		assert.Equal(t, mask, bs[offset], "index %d", i)
+		// the bit read through the location is the one read by getBit
+		bs[offset] ^= mask
+		assert.False(t, bs.getBit(i), "index %d", i)
+		bs[offset] |= mask
+		assert.True(t, bs.getBit(i), "index %d", i)
+		bs.setBit(i, false)
+		assert.Zero(t, bs[offset], "index %d", i)
+	}
+}
+
+func TestRevocationList2020_StatusFor(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+
+	cs, err := rl.StatusFor(94567)
+	assert.NoError(t, err)
+	assert.Equal(t, NewCredentialStatus("c0", 94567), cs)
+	assert.True(t, rl.Owns(cs))
+
+	_, err = rl.StatusFor(131072)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+	assert.EqualError(t, err, "credential index out of range 0-131072: 131072")
+	_, err = rl.StatusFor(-1)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+
+	// the status of a suspension list carries its purpose
+	sl, _ := NewRevocationList("c0", 16, WithStatusPurpose(PurposeSuspension))
+	cs, err = sl.StatusFor(10)
+	assert.NoError(t, err)
+	assert.Equal(t, PurposeSuspension, cs.(CredentialStatusJSON).StatusPurpose)
+	assert.NoError(t, sl.Revoke(10))
+	isIt, err := sl.IsRevokedAny([]CredentialStatus{cs})
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+}
+
+func TestRevocationList2020_Extend(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(5, 131071))
+	assert.ErrorIs(t, rl.Revoke(131072), ErrIndexOutOfRange)
+
+	assert.NoError(t, rl.Extend(16))
+	assert.Equal(t, 32, rl.Size())
+	assert.Equal(t, []int{5, 131071}, rl.RevokedIndexes())
+	// the new indexes are addressable
+	assert.NoError(t, rl.Revoke(131072, 262143))
+	assert.Equal(t, []int{5, 131071, 131072, 262143}, rl.RevokedIndexes())
+	assert.False(t, rl.IsDirty())
+
+	assert.EqualError(t, rl.Extend(0), "cannot extend the list by 0kb")
+	assert.EqualError(t, rl.Extend(97), "size out of bounds: must be between 16 and 128, got 129")
+	assert.Equal(t, 32, rl.Size())
+}
+
+func TestRevocationList2020_RevokedRanges(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		revoke [][2]int
+		want   [][2]int
+	}{
+		{"PASS: empty list", nil, [][2]int{}},
+		{"PASS: scattered revocations", [][2]int{{1, 2}, {3, 4}, {100, 101}, {131071, 131072}}, [][2]int{{1, 2}, {3, 4}, {100, 101}, {131071, 131072}}},
+		{"PASS: contiguous blocks", [][2]int{{0, 8}, {10, 1000}, {5000, 5001}}, [][2]int{{0, 8}, {10, 1000}, {5000, 5001}}},
+		{"PASS: adjacent blocks coalesce", [][2]int{{5, 8}, {8, 17}, {17, 18}}, [][2]int{{5, 18}}},
+		{"PASS: whole list", [][2]int{{0, 131072}}, [][2]int{{0, 131072}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := NewRevocationList("c0", 16)
+			for _, r := range tt.revoke {
+				assert.NoError(t, rl.RevokeRange(r[0], r[1]))
+			}
+			assert.Equal(t, tt.want, rl.RevokedRanges())
+		})
+	}
+}
+
+func TestNewBitSet_ExtremeSizes(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		new     func() error
+		wantErr string
+	}{
+		{"FAIL: negative size", func() error { _, err := newBitSet(-1); return err }, "size out of bounds: cannot allocate -1kb"},
+		{"FAIL: zero size", func() error { _, err := newBitSet(0); return err }, "size out of bounds: cannot allocate 0kb"},
+		{"FAIL: overflowing size", func() error { _, err := newBitSet(math.MaxInt); return err }, fmt.Sprintf("size out of bounds: cannot allocate %dkb", math.MaxInt)},
+		{"FAIL: overflowing max size", func() error { _, err := NewRevocationList("c0", 16, WithMaxSize(math.MaxInt)); return err }, fmt.Sprintf("size out of bounds: max size must be at most %d, got %d", maxAllocSize, math.MaxInt)},
+		{"FAIL: negative list size", func() error { _, err := NewRevocationList("c0", math.MinInt); return err }, fmt.Sprintf("size out of bounds: must be between 16 and 128, got %d", math.MinInt)},
+		{"FAIL: huge list size", func() error { _, err := NewRevocationList("c0", math.MaxInt); return err }, fmt.Sprintf("size out of bounds: must be between 16 and 128, got %d", math.MaxInt)},
+		{"FAIL: huge capacity", func() error { _, err := NewRevocationListWithBits("c0", math.MaxInt); return err }, fmt.Sprintf("size out of bounds: must be between 1 and 1048576 bits, got %d", math.MaxInt)},
+		{"FAIL: huge capacity within the max size", func() error {
+			_, err := NewRevocationListWithBits("c0", math.MaxInt, WithMaxSize(maxAllocSize))
+			return err
+		}, fmt.Sprintf("size out of bounds: must be between 1 and %d bits, got %d", maxAllocSize*8*1024, math.MaxInt)},
+		{"FAIL: huge capacity for the size", func() error { _, err := KBSizeForCapacity(math.MaxInt, WithMaxSize(maxAllocSize)); return err }, fmt.Sprintf("size out of bounds: capacity must be between 0 and %d, got %d", maxAllocSize*8*1024, math.MaxInt)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.new()
+			assert.ErrorIs(t, err, ErrSizeOutOfBounds)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	bs, err := newBitSet(16)
+	assert.NoError(t, err)
+	assert.Len(t, bs, 16*1024)
+}
+
+func TestPack_Uniform(t *testing.T) {
+
+	tests := []struct {
+		name string
+		fill uint8
+		opts []Option
+	}{
+		{"PASS: all zeros", 0x00, nil},
+		{"PASS: all ones", 0xff, nil},
+		{"PASS: all zeros gzip", 0x00, []Option{WithCompression(Gzip)}},
+		{"PASS: all ones url encoding", 0xff, []Option{WithURLEncoding(), WithCompressionLevel(zlib.BestSpeed)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, _ := newOptions(tt.opts...)
+			bs, _ := newBitSet(64)
+			for i := range bs {
+				bs[i] = tt.fill
+			}
+			s, err := pack(bs, o)
+			assert.NoError(t, err)
+			// the cached encoding is the same as the compressed one
+			var bb bytes.Buffer
+			assert.NoError(t, compressContext(context.Background(), &bb, bs, o))
+			assert.Equal(t, o.encoding.EncodeToString(bb.Bytes()), s)
+			again, _ := pack(bs, o)
+			assert.Equal(t, s, again)
+
+			// the list is expanded with the detected options
+			uo, _ := newOptions()
+			got, err := unpack(s, &uo)
+			assert.NoError(t, err)
+			assert.Equal(t, bs, got)
+			assert.Equal(t, o.encoding, uo.encoding)
+			assert.Equal(t, o.compression, uo.compression)
+			// the expanded list is not shared
+			got[0] ^= 0x01
+			got, _ = unpack(s, &uo)
+			assert.Equal(t, bs, got)
+
+			// the limits are still enforced
+			lo, _ := newOptions(WithDecompressionLimit(1024))
+			_, err = unpack(s, &lo)
+			assert.EqualError(t, err, "decompressed list exceeds the limit of 1024 bytes")
+
+			// the compression asked by the options is still honored
+			co, _ := newOptions(WithCompression(Uncompressed))
+			got, err = unpack(s, &co)
+			assert.NoError(t, err)
+			assert.NotEqual(t, bs, got)
+		})
+	}
+
+	// a list that is no longer uniform is compressed
+	rl, _ := NewRevocationList("c0", 16)
+	empty := rl.EncodedList
+	assert.NoError(t, rl.Revoke(1))
+	assert.NotEqual(t, empty, rl.EncodedList)
+	assert.NoError(t, rl.Reset(1))
+	assert.Equal(t, empty, rl.EncodedList)
+
+	// cancelled contexts are honored
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := packContext(ctx, rl.bitSet, rl.opts)
+	assert.ErrorIs(t, err, context.Canceled)
+	o := rl.opts
+	_, err = unpackContext(ctx, empty, &o)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func BenchmarkPack_Uniform(b *testing.B) {
+	o, _ := newOptions()
+	empty, _ := newBitSet(128)
+	b.Run("empty", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = pack(empty, o)
+		}
+	})
+	sparse, _ := newBitSet(128)
+	sparse[len(sparse)-1] = 0x01
+	b.Run("sparse", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = pack(sparse, o)
+		}
+	})
+}
+
+func BenchmarkUnpack_Uniform(b *testing.B) {
+	o, _ := newOptions()
+	empty, _ := newBitSet(128)
+	s, _ := pack(empty, o)
+	b.Run("empty", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			uo := o
+			_, _ = unpack(s, &uo)
+		}
+	})
+	sparse, _ := newBitSet(128)
+	sparse[len(sparse)-1] = 0x01
+	s, _ = pack(sparse, o)
+	b.Run("sparse", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			uo := o
+			_, _ = unpack(s, &uo)
+		}
+	})
+}
+
+func TestRevocationList2020_IsRevokedByIndex(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(0, 94567))
+
+	tests := []struct {
+		name    string
+		index   int
+		want    bool
+		wantErr error
+	}{
+		{"PASS: revoked", 94567, true, nil},
+		{"PASS: first index revoked", 0, true, nil},
+		{"PASS: not revoked", 1, false, nil},
+		{"PASS: last index", 131071, false, nil},
+		{"FAIL: index too big", 131072, false, fmt.Errorf("credential index out of range 0-131072: 131072")},
+		{"FAIL: negative index", -1, false, fmt.Errorf("credential index out of range 0-131072: -1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rl.IsRevokedByIndex(tt.index)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, ErrIndexOutOfRange)
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRevocationList2020_ExpectCapacity(t *testing.T) {
+	rl16, _ := NewRevocationList("c0", 16)
+	rl32, _ := NewRevocationList("c1", 32)
+	rlBits, _ := NewRevocationListWithBits("c2", 100003)
+
+	tests := []struct {
+		name    string
+		rl      RevocationList2020
+		n       int
+		wantErr error
+	}{
+		{"PASS: 16kb list", rl16, 131072, nil},
+		{"PASS: exact capacity", rlBits, 100003, nil},
+		{"FAIL: bigger list", rl32, 131072, fmt.Errorf("revocation list c1 has a capacity of 262144 credentials, expected 131072")},
+		{"FAIL: smaller list", rl16, 262144, fmt.Errorf("revocation list c0 has a capacity of 131072 credentials, expected 262144")},
+		{"FAIL: whole kb of an exact capacity", rlBits, 131072, fmt.Errorf("revocation list c2 has a capacity of 100003 credentials, expected 131072")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rl.ExpectCapacity(tt.n)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRevocationList2020_RevokeStatus(t *testing.T) {
+	rl, _ := NewRevocationList("https://example.com/credentials/status/3", 16)
+
+	tests := []struct {
+		name    string
+		status  CredentialStatus
+		wantErr error
+	}{
+		{"PASS: status of the list", NewCredentialStatus("https://example.com/credentials/status/3", 94567), nil},
+		{"FAIL: status of another list", NewCredentialStatus("https://example.com/credentials/status/4", 94567), fmt.Errorf("wrong revocation list, expected https://example.com/credentials/status/3, got https://example.com/credentials/status/4")},
+		{"FAIL: index out of range", NewCredentialStatus("https://example.com/credentials/status/3", 131072), fmt.Errorf("credential index out of range 0-131072: 131072")},
+		{"FAIL: unsupported type", CredentialStatusJSON{ID: "https://example.com/credentials/status/3/1", Type: "StatusList2021Entry", RevocationListCredential: "https://example.com/credentials/status/3", RevocationListIndex: 1}, fmt.Errorf("unsupported type StatusList2021Entry, expected RevocationList2020Status")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rl.RevokeStatus(tt.status)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+				assert.EqualError(t, rl.ResetStatus(tt.status), tt.wantErr.Error())
+				assert.Empty(t, rl.RevokedIndexes())
+				return
+			}
+			assert.NoError(t, err)
+			isIt, err := rl.IsRevoked(tt.status)
+			assert.NoError(t, err)
+			assert.True(t, isIt)
+			assert.NoError(t, rl.ResetStatus(tt.status))
+			isIt, _ = rl.IsRevoked(tt.status)
+			assert.False(t, isIt)
+		})
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	encodedList := "eJzsxjERAAAIBCAj2D+tkyH+DyYGqLEfAAAAAAAAAAAAAAAAAAAgzg0AAzwAEQ=="
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"PASS: no schema version", fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s"}`, encodedList), nil},
+		{"PASS: current schema version", fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s","schemaVersion":1}`, encodedList), nil},
+		{"FAIL: future schema version", fmt.Sprintf(`{"id":"c0","type":"RevocationList2020","encodedList":"%s","schemaVersion":2}`, encodedList), fmt.Errorf("unsupported schema version 2, expected at most 1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := NewRevocationListFromJSON([]byte(tt.data))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, ErrUnsupportedSchema)
+				assert.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []int{7812}, rl.RevokedIndexes())
+			assert.Nil(t, rl.Extras)
+		})
+	}
+
+	// only the extended serializations are tagged
+	rl, _ := NewRevocationList("c0", 16)
+	data, _ := json.Marshal(&rl)
+	assert.NotContains(t, string(data), "schemaVersion")
+	rl, _ = NewRevocationList("c0", 16, WithRevision())
+	assert.NoError(t, rl.Revoke(1))
+	data, _ = json.Marshal(&rl)
+	assert.Contains(t, string(data), `"schemaVersion":1`)
+}
+
+func TestRevocationList2020_Seal(t *testing.T) {
+	rl, _ := NewRevocationList("c0", 16)
+	assert.NoError(t, rl.Revoke(1, 2))
+	other, _ := NewRevocationList("c0", 16)
+	otherJSON, _ := json.Marshal(other)
+	otherBinary, _ := other.MarshalBinary()
+	otherYAML, _ := yaml.Marshal(other)
+	snapshot := rl.Snapshot()
+	encodedList := rl.EncodedList
+	assert.False(t, rl.IsSealed())
+
+	rl.Seal()
+	assert.True(t, rl.IsSealed())
+
+	tests := []struct {
+		name   string
+		update func(rl *RevocationList2020) error
+	}{
+		{"FAIL: revoke", func(rl *RevocationList2020) error { return rl.Revoke(3) }},
+		{"FAIL: reset", func(rl *RevocationList2020) error { return rl.Reset(1) }},
+		{"FAIL: revoke status", func(rl *RevocationList2020) error { return rl.RevokeStatus(NewCredentialStatus("c0", 3)) }},
+		{"FAIL: toggle", func(rl *RevocationList2020) error { return rl.Toggle(1) }},
+		{"FAIL: try update", func(rl *RevocationList2020) error { return rl.TryUpdate(Revoke, 3) }},
+		{"FAIL: update sorted", func(rl *RevocationList2020) error { return rl.UpdateSorted(Revoke, 3, 4) }},
+		{"FAIL: revoke range", func(rl *RevocationList2020) error { return rl.RevokeRange(3, 10) }},
+		{"FAIL: reset all", func(rl *RevocationList2020) error { return rl.ResetAll() }},
+		{"FAIL: merge", func(rl *RevocationList2020) error { return rl.Merge(&other) }},
+		{"FAIL: resize", func(rl *RevocationList2020) error { return rl.Resize(32) }},
+		{"FAIL: extend", func(rl *RevocationList2020) error { return rl.Extend(16) }},
+		{"FAIL: restore snapshot", func(rl *RevocationList2020) error { return rl.RestoreSnapshot(make([]byte, len(snapshot))) }},
+		{"FAIL: unmarshal json", func(rl *RevocationList2020) error { return json.Unmarshal(otherJSON, rl) }},
+		{"FAIL: unmarshal binary", func(rl *RevocationList2020) error { return rl.UnmarshalBinary(otherBinary) }},
+		{"FAIL: unmarshal yaml", func(rl *RevocationList2020) error { return yaml.Unmarshal(otherYAML, rl) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.update(&rl)
+			assert.ErrorIs(t, err, ErrSealed)
+			assert.EqualError(t, err, "revocation list sealed, c0 cannot be modified")
+			assert.Equal(t, []int{1, 2}, rl.RevokedIndexes())
+			assert.Equal(t, encodedList, rl.EncodedList)
+			assert.Equal(t, 16, rl.Size())
+
+			// the clone is not sealed
+			c := rl.Clone()
+			assert.False(t, c.IsSealed())
+			assert.NoError(t, tt.update(&c))
+		})
+	}
+
+	// reading a sealed list still works
+	isIt, err := rl.IsRevoked(NewCredentialStatus("c0", 1))
+	assert.NoError(t, err)
+	assert.True(t, isIt)
+	_, err = rl.GetBytes()
+	assert.NoError(t, err)
+}
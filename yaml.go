@@ -0,0 +1,150 @@
+package rl2020
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NewRevocationListFromYAML parses a yaml serialized revocation list, with the same
+// fields as the json serialization
+func NewRevocationListFromYAML(data []byte, opts ...Option) (rl RevocationList2020, err error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(data, &node); err != nil {
+		return
+	}
+	err = rl.unmarshalYAML(&node, o)
+	return
+}
+
+// MarshalYAML serializes the revocation list with the same fields as MarshalJSON
+func (rl RevocationList2020) MarshalYAML() (any, error) {
+	defer rl.rLock()()
+	f, err := rl.serialized()
+	if err != nil || len(rl.Extras) == 0 {
+		return f, err
+	}
+	var node yaml.Node
+	if err = node.Encode(f); err != nil {
+		return nil, err
+	}
+	// append the extra properties in a stable order, without overriding the standard ones
+	keys := make([]string, 0, len(rl.Extras))
+	for k := range rl.Extras {
+		if !serializedFields[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var v any
+		if err = json.Unmarshal(rl.Extras[k], &v); err != nil {
+			return nil, err
+		}
+		var value yaml.Node
+		if err = value.Encode(v); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, &value)
+	}
+	return &node, nil
+}
+
+// UnmarshalYAML parses a yaml serialized revocation list using the default options
+func (rl *RevocationList2020) UnmarshalYAML(value *yaml.Node) error {
+	o, err := newOptions()
+	if err != nil {
+		return err
+	}
+	return rl.unmarshalYAML(value, o)
+}
+
+func (rl *RevocationList2020) unmarshalYAML(value *yaml.Node, o options) (err error) {
+	if value.Kind == yaml.DocumentNode && len(value.Content) == 1 {
+		value = value.Content[0]
+	}
+	if value.Kind != yaml.MappingNode {
+		// let the decoder report the error
+		return value.Decode(&serializedList{})
+	}
+	// split the type, that may also be an array, and the extra properties from the fields
+	fields := *value
+	fields.Content = nil
+	var listType any
+	var extras map[string]json.RawMessage
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		k, v := value.Content[i], value.Content[i+1]
+		switch {
+		case k.Value == "type":
+			if err = v.Decode(&listType); err != nil {
+				return
+			}
+		case serializedFields[k.Value]:
+			fields.Content = append(fields.Content, k, v)
+		default:
+			if extras == nil {
+				extras = make(map[string]json.RawMessage)
+			}
+			var extra any
+			if err = v.Decode(&extra); err != nil {
+				return
+			}
+			if extras[k.Value], err = json.Marshal(extra); err != nil {
+				return
+			}
+		}
+	}
+	var f serializedList
+	if err = fields.Decode(&f); err != nil {
+		return
+	}
+	if err = checkSchemaVersion(f.SchemaVersion); err != nil {
+		return
+	}
+	if err = o.validateID(f.ID); err != nil {
+		return
+	}
+	rawType, err := json.Marshal(listType)
+	if err != nil {
+		return
+	}
+	if f.Type, err = parseListType(rawType); err != nil {
+		return
+	}
+	list := RevocationList2020{
+		ID:          f.ID,
+		Type:        f.Type,
+		Purpose:     f.Purpose,
+		Issuer:      f.Issuer,
+		EncodedList: f.EncodedList,
+		Extras:      extras,
+		revision:    f.Revision,
+		bits:        f.Capacity,
+	}
+	for _, d := range []struct {
+		t *time.Time
+		v string
+	}{
+		{&list.IssuanceDate, f.IssuanceDate},
+		{&list.ValidFrom, f.ValidFrom},
+		{&list.ValidUntil, f.ValidUntil},
+	} {
+		if d.v == "" {
+			continue
+		}
+		if *d.t, err = time.Parse(time.RFC3339, d.v); err != nil {
+			return
+		}
+	}
+	if err = list.decodeList(context.Background(), o); err != nil {
+		return
+	}
+	return rl.replace(list)
+}
@@ -0,0 +1,112 @@
+package rl2020
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRevocationList2020_YAML(t *testing.T) {
+	issued := time.Date(2020, 4, 5, 14, 27, 40, 0, time.UTC)
+	rl, _ := NewRevocationListWithIssuer("https://example.com/credentials/status/3", 16, "did:example:12345", issued, WithRevision())
+	assert.NoError(t, rl.Revoke(1, 1000, 131071))
+
+	data, err := yaml.Marshal(&rl)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`id: https://example.com/credentials/status/3
+type: RevocationList2020
+issuer: did:example:12345
+issuanceDate: "2020-04-05T14:27:40Z"
+encodedList: %s
+revision: 1
+schemaVersion: 1
+`, rl.EncodedList), string(data))
+
+	got, err := NewRevocationListFromYAML(data, WithRevision())
+	assert.NoError(t, err)
+	assert.Equal(t, rl, got)
+
+	// nested in another document
+	var doc struct {
+		List RevocationList2020 `yaml:"list"`
+	}
+	assert.NoError(t, yaml.Unmarshal([]byte(fmt.Sprintf("list:\n  id: c0\n  type: RevocationList2020\n  issuanceDate: 2020-04-05T14:27:40Z\n  encodedList: %s\n", rl.EncodedList)), &doc))
+	assert.Equal(t, []int{1, 1000, 131071}, doc.List.RevokedIndexes())
+	assert.Equal(t, issued, doc.List.IssuanceDate)
+
+	// the capacity of the list is preserved
+	rl, _ = NewRevocationListWithBits("c0", 100000)
+	data, err = yaml.Marshal(&rl)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "capacity: 100000\n")
+	got, err = NewRevocationListFromYAML(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 100000, got.Capacity())
+
+	// the type may be an array and the extra properties are preserved
+	got, err = NewRevocationListFromYAML([]byte(fmt.Sprintf(`id: c0
+type: [RevocationList2020, Extended]
+encodedList: %s
+region: eu-west
+version: 2
+tags: [a, b]
+`, rl.EncodedList)))
+	assert.NoError(t, err)
+	assert.Equal(t, TypeRevocationList2020, got.Type)
+	assert.Equal(t, map[string]json.RawMessage{
+		"region":  json.RawMessage(`"eu-west"`),
+		"tags":    json.RawMessage(`["a","b"]`),
+		"version": json.RawMessage(`2`),
+	}, got.Extras)
+	data, err = yaml.Marshal(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`id: c0
+type: RevocationList2020
+encodedList: %s
+region: eu-west
+tags:
+    - a
+    - b
+version: 2
+`, rl.EncodedList), string(data))
+	// the extras read from json are written to yaml and back
+	js, err := json.Marshal(&got)
+	assert.NoError(t, err)
+	fromJSON, err := NewRevocationListFromJSON(js)
+	assert.NoError(t, err)
+	data, err = yaml.Marshal(&fromJSON)
+	assert.NoError(t, err)
+	again, err := NewRevocationListFromYAML(data)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Extras, again.Extras)
+	// the extras do not override the standard properties
+	again.Extras["encodedList"] = json.RawMessage(`"bogus"`)
+	data, err = yaml.Marshal(&again)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "bogus")
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"FAIL: empty id", "id: ''\ntype: RevocationList2020\nencodedList: " + rl.EncodedList, fmt.Errorf("revocation list ID is empty")},
+		{"FAIL: wrong type", "id: c0\ntype: StatusList2021\nencodedList: " + rl.EncodedList, fmt.Errorf("unsupported type StatusList2021, expected RevocationList2020")},
+		{"FAIL: wrong type array", "id: c0\ntype: [StatusList2021]\nencodedList: " + rl.EncodedList, fmt.Errorf("unsupported type [StatusList2021], expected RevocationList2020")},
+		{"FAIL: missing type", "id: c0\nencodedList: " + rl.EncodedList, fmt.Errorf("unsupported type , expected RevocationList2020")},
+		{"FAIL: not a mapping", "[c0]", fmt.Errorf("yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into rl2020.serializedList")},
+		{"FAIL: size out of range", "id: c0\ntype: RevocationList2020\nencodedList: eJxjYBgFo2AUjFQAAAQAAAE=", fmt.Errorf("size out of bounds: must be between %d and %d, got %d", minBitSetSize, maxBitSetSize, 1)},
+		{"FAIL: future schema version", "id: c0\ntype: RevocationList2020\nschemaVersion: 2\nencodedList: " + rl.EncodedList, fmt.Errorf("unsupported schema version 2, expected at most 1")},
+		{"FAIL: invalid date", "id: c0\ntype: RevocationList2020\nvalidUntil: tomorrow\nencodedList: " + rl.EncodedList, fmt.Errorf(`parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRevocationListFromYAML([]byte(tt.data))
+			assert.EqualError(t, err, tt.wantErr.Error())
+		})
+	}
+}